@@ -0,0 +1,60 @@
+package go_loadgen
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAggregatingObserverSummarizesRun(t *testing.T) {
+	observer := NewAggregatingObserver()
+	var calls atomic.Uint64
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(2 * time.Millisecond)
+		return testResult{failed: calls.Add(1)%4 == 0}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Observers: []Observer{observer},
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 20 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+
+	snapshot := observer.Snapshot()
+	if snapshot.Requests != report.Completed || snapshot.Failed != report.Failed || snapshot.ErrorRate != float64(report.Failed)/float64(report.Completed) {
+		t.Fatalf("snapshot=%+v report=%+v", snapshot, report.Counts)
+	}
+	if p50 := snapshot.Percentile(50); p50 < 1800*time.Microsecond || snapshot.Mean < 2*time.Millisecond || snapshot.Max < snapshot.Percentile(99) {
+		t.Fatalf("p50=%s mean=%s max=%s, want latencies around the 2ms client call", p50, snapshot.Mean, snapshot.Max)
+	}
+
+	observer.Reset()
+	if snapshot := observer.Snapshot(); snapshot.Requests != 0 || snapshot.Max != 0 || snapshot.Percentile(99) != 0 {
+		t.Fatalf("snapshot=%+v after reset", snapshot)
+	}
+}
+
+func TestAggregatingObserverPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		observer  *AggregatingObserver
+		tolerance float64
+	}{
+		{NewAggregatingObserver(), 0.03},
+		{NewAggregatingObserver(WithLatencyPrecision(3)), 0.001},
+	} {
+		for i := 1; i <= 100_000; i++ {
+			tc.observer.Observe(Sample{Latency: time.Duration(i) * time.Microsecond})
+		}
+		snapshot := tc.observer.Snapshot()
+		for _, percentile := range []float64{50, 99, 99.9, 99.99} {
+			want := time.Duration(percentile * 1000 * float64(time.Microsecond))
+			got := snapshot.Percentile(percentile)
+			if diff := math.Abs(float64(got-want)) / float64(want); diff > tc.tolerance {
+				t.Errorf("p%g=%s, want within %g of %s", percentile, got, tc.tolerance, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package go_loadgen
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Arrivals selects how a phase spaces arrivals at its offered rate.
+type Arrivals uint8
+
+const (
+	// UniformArrivals spaces arrivals evenly. It is the default.
+	UniformArrivals Arrivals = iota
+	// PoissonArrivals draws exponential inter-arrival gaps with the phase's rate as
+	// the mean, modelling independent clients and exposing queueing on the target.
+	PoissonArrivals
+)
+
+// Batch is a group of arrivals issued together. At and Deadline are offsets
+// from the phase start; a batch the scheduler only reaches at or after its
+// Deadline is reported as missed. A zero Deadline allows one scheduler resolution.
+type Batch struct {
+	At       time.Duration
+	Count    uint64
+	Deadline time.Duration
+}
+
+// Pacer produces a phase's arrival batches in ascending order. Next reports
+// false when the schedule ends; batches after the phase end are never issued.
+// Every phase schedule is a Pacer, and Phase.Pacer plugs in custom ones.
+type Pacer interface {
+	Next() (Batch, bool)
+}
+
+func (p *compiledPhase) newPacer() Pacer {
+	if p.phase.Pacer != nil {
+		pacer := p.phase.Pacer()
+		if bucket, ok := pacer.(*tokenBucket); ok {
+			// A fresh bucket keeps the factory's instance untouched, should
+			// it be shared between phases of different resolutions.
+			return &tokenBucket{rps: bucket.rps, burst: bucket.burst, resolution: p.resolution}
+		}
+		return pacer
+	}
+	if len(p.phase.Trace) != 0 {
+		return &tracePacer{trace: p.phase.Trace, resolution: p.resolution}
+	}
+	if p.phase.Burst != nil {
+		return &burstPacer{burst: *p.phase.Burst, duration: p.phase.Duration}
+	}
+	if p.phase.Arrivals == PoissonArrivals {
+		poisson := &poissonPacer{phase: p, random: phaseRandom{state: splitMix64(p.seed)}}
+		poisson.pending = poisson.gap(0)
+		return poisson
+	}
+	return &uniformPacer{phase: p}
+}
+
+type uniformPacer struct {
+	phase     *compiledPhase
+	at        time.Duration
+	remainder uint64
+}
+
+func (p *uniformPacer) Next() (Batch, bool) {
+	rate := p.phase.rateAt(p.at)
+	interval := batchInterval(rate, p.phase.resolution)
+	p.at += interval
+	if p.at > p.phase.phase.Duration {
+		return Batch{}, false
+	}
+	return Batch{At: p.at, Count: arrivalsForInterval(rate, interval, &p.remainder), Deadline: p.at + interval}, true
+}
+
+// poissonPacer groups arrivals that fall within one scheduler resolution so high
+// rates do not require a timer per request.
+type poissonPacer struct {
+	phase   *compiledPhase
+	random  phaseRandom
+	pending time.Duration
+}
+
+func (p *poissonPacer) Next() (Batch, bool) {
+	if p.pending > p.phase.phase.Duration {
+		return Batch{}, false
+	}
+	batch := Batch{At: p.pending}
+	for p.pending <= p.phase.phase.Duration && p.pending < batch.At+p.phase.resolution {
+		batch.Count++
+		p.pending = p.gap(p.pending)
+	}
+	batch.Deadline = max(p.pending, batch.At+p.phase.resolution)
+	return batch, true
+}
+
+// gap returns the arrival following the one at elapsed.
+func (p *poissonPacer) gap(elapsed time.Duration) time.Duration {
+	uniform := (float64(p.random.next()>>11) + 1) / (1 << 53)
+	seconds := -math.Log(uniform) / float64(p.phase.rateAt(elapsed))
+	if seconds >= float64(p.phase.phase.Duration-elapsed)/float64(time.Second) {
+		return p.phase.phase.Duration + 1
+	}
+	return elapsed + max(time.Duration(seconds*float64(time.Second)), 1)
+}
+
+type tracePacer struct {
+	trace      []time.Duration
+	resolution time.Duration
+	index      int
+}
+
+func (p *tracePacer) Next() (Batch, bool) {
+	if p.index == len(p.trace) {
+		return Batch{}, false
+	}
+	batch := Batch{At: p.trace[p.index]}
+	for p.index < len(p.trace) && p.trace[p.index] < batch.At+p.resolution {
+		batch.Count++
+		p.index++
+	}
+	batch.Deadline = batch.At + p.resolution
+	if p.index < len(p.trace) {
+		batch.Deadline = max(batch.Deadline, p.trace[p.index])
+	}
+	return batch, true
+}
+
+// PacerFactory validates a phase that names a registered schedule and returns
+// the constructor called at the start of each run.
+type PacerFactory func(Phase) (func() Pacer, error)
+
+var pacerRegistry = struct {
+	sync.RWMutex
+	factories map[string]PacerFactory
+}{factories: make(map[string]PacerFactory)}
+
+// RegisterPacer makes a custom schedule available to phases through
+// Phase.Schedule, so workloads can select it by name. Names are global and can
+// be registered once.
+func RegisterPacer(name string, factory PacerFactory) error {
+	if name == "" || factory == nil {
+		return errors.New("pacer name and factory must be non-empty")
+	}
+	pacerRegistry.Lock()
+	defer pacerRegistry.Unlock()
+	if _, ok := pacerRegistry.factories[name]; ok {
+		return fmt.Errorf("pacer %q is already registered", name)
+	}
+	pacerRegistry.factories[name] = factory
+	return nil
+}
+
+func registeredPacer(phase Phase) (func() Pacer, error) {
+	pacerRegistry.RLock()
+	factory, ok := pacerRegistry.factories[phase.Schedule]
+	pacerRegistry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("schedule %q is not registered", phase.Schedule)
+	}
+	pacer, err := factory(phase)
+	if err == nil && pacer == nil {
+		err = fmt.Errorf("schedule %q returned no pacer", phase.Schedule)
+	}
+	return pacer, err
+}
+
+// NewTokenBucket returns a pacer that issues burst arrivals when the phase
+// starts and then rps arrivals per second: the schedule of a token bucket with
+// capacity burst that is drained as soon as tokens become available. In a
+// phase, arrivals are batched at the workload's Spec.Resolution, and the
+// phase's schedule starts from a fresh bucket. A zero rps is raised to one.
+func NewTokenBucket(rps, burst uint64) Pacer {
+	return &tokenBucket{rps: max(rps, 1), burst: burst}
+}
+
+type tokenBucket struct {
+	rps   uint64
+	burst uint64
+	// resolution is the batching interval of the phase using the bucket;
+	// zero means the default.
+	resolution time.Duration
+	started    bool
+	at         time.Duration
+	remainder  uint64
+}
+
+func (b *tokenBucket) Next() (Batch, bool) {
+	interval := batchInterval(b.rps, cmp.Or(b.resolution, defaultResolution))
+	if !b.started && b.burst > 0 {
+		b.started = true
+		return Batch{Count: b.burst, Deadline: interval}, true
+	}
+	b.at += interval
+	return Batch{At: b.at, Count: arrivalsForInterval(b.rps, interval, &b.remainder), Deadline: b.at + interval}, true
+}
+
+type burstPacer struct {
+	burst    Burst
+	duration time.Duration
+	at       time.Duration
+}
+
+func (p *burstPacer) Next() (Batch, bool) {
+	if p.at >= p.duration {
+		return Batch{}, false
+	}
+	batch := Batch{At: p.at, Count: p.burst.Size, Deadline: p.at + p.burst.Every}
+	p.at += p.burst.Every
+	return batch, true
+}
+
+func validatePacer(phase Phase) error {
+	if phase.Schedule != "" {
+		if phase.Pacer != nil || phase.Users != 0 || phase.Burst != nil || len(phase.Trace) != 0 {
+			return errors.New("registered schedules cannot set a pacer, users, bursts, or traces")
+		}
+		return nil
+	}
+	if phase.RPS != 0 || phase.Ramp != nil || phase.Arrivals != UniformArrivals || phase.Users != 0 || phase.Burst != nil || len(phase.Trace) != 0 {
+		return errors.New("custom pacer phases cannot set RPS, ramp, arrivals, users, bursts, or traces")
+	}
+	return nil
+}
+
+func validateBurst(phase Phase) error {
+	if phase.RPS != 0 || phase.Ramp != nil || phase.Arrivals != UniformArrivals || phase.Users != 0 {
+		return errors.New("burst phases cannot set RPS, ramp, arrivals, or users")
+	}
+	if phase.Burst.Size == 0 || phase.Burst.Every <= 0 {
+		return errors.New("burst size and interval must be positive")
+	}
+	return nil
+}
+
+func validateTrace(phase Phase) error {
+	if phase.RPS != 0 || phase.Ramp != nil || phase.Arrivals != UniformArrivals || phase.Users != 0 || phase.Burst != nil {
+		return errors.New("trace phases cannot set RPS, ramp, arrivals, users, or bursts")
+	}
+	for i, offset := range phase.Trace {
+		if offset < 0 || offset > phase.Duration {
+			return fmt.Errorf("trace offset %d is outside the phase", i)
+		}
+		if i > 0 && offset < phase.Trace[i-1] {
+			return fmt.Errorf("trace offset %d is out of order", i)
+		}
+	}
+	return nil
+}
+
+// ReadTrace parses arrival offsets, one per line, for Phase.Trace. Lines hold a
+// Go duration such as "1.5s" or a number of seconds; blank lines and lines
+// starting with '#' are ignored. Offsets are sorted so captured logs need not be.
+func ReadTrace(reader io.Reader) ([]time.Duration, error) {
+	var trace []time.Duration
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		offset, err := time.ParseDuration(text)
+		if err != nil {
+			seconds, parseErr := strconv.ParseFloat(text, 64)
+			if parseErr != nil {
+				return nil, fmt.Errorf("trace line %d: invalid offset %q", line, text)
+			}
+			offset = time.Duration(seconds * float64(time.Second))
+		}
+		trace = append(trace, offset)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(trace)
+	return trace, nil
+}
+
+// accessLogLayout is the timestamp layout of the Common and Combined Log
+// Formats.
+const accessLogLayout = "02/Jan/2006:15:04:05 -0700"
+
+// ReadRequestLog parses request timestamps from an access log and returns them
+// as arrival offsets from the earliest request, for Phase.Trace or
+// TracePhases. Lines are in the Common or Combined Log Format, whose timestamp
+// is in brackets, or are CSV rows whose first column is an RFC 3339 time or
+// Unix seconds. Blank lines, lines starting with '#', and a CSV header row are
+// ignored.
+func ReadRequestLog(reader io.Reader) ([]time.Duration, error) {
+	var times []time.Time
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	header := true
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		at, ok := parseRequestTime(text)
+		if !ok {
+			if header {
+				header = false
+				continue
+			}
+			return nil, fmt.Errorf("request log line %d: no timestamp", line)
+		}
+		header = false
+		times = append(times, at)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, nil
+	}
+	first := slices.MinFunc(times, time.Time.Compare)
+	trace := make([]time.Duration, len(times))
+	for i, at := range times {
+		trace[i] = at.Sub(first)
+	}
+	slices.Sort(trace)
+	return trace, nil
+}
+
+func parseRequestTime(line string) (time.Time, bool) {
+	if start := strings.IndexByte(line, '['); start >= 0 {
+		if end := strings.IndexByte(line[start:], ']'); end > 0 {
+			at, err := time.Parse(accessLogLayout, line[start+1:start+end])
+			return at, err == nil
+		}
+	}
+	field, _, _ := strings.Cut(line, ",")
+	field = strings.Trim(strings.TrimSpace(field), `"`)
+	if at, err := time.Parse(time.RFC3339Nano, field); err == nil {
+		return at, true
+	}
+	seconds, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second))), true
+}
+
+// ReadInvocationCounts parses the per-minute invocation counts of the public
+// Azure Functions trace, whose CSV rows are one function each: identifying
+// columns such as HashOwner and Trigger, then one column per minute headed by
+// its number. Every function's counts are summed, so the result is the total
+// invocations in each minute, for CountPhases. Only this layout, from the
+// 2019 Azure Functions dataset, is parsed; the Google and Alibaba cluster
+// traces use other formats and are not supported.
+func ReadInvocationCounts(reader io.Reader) ([]uint64, error) {
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = -1
+	header, err := records.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read invocation header: %w", err)
+	}
+	first := slices.IndexFunc(header, func(name string) bool {
+		_, err := strconv.Atoi(strings.TrimSpace(name))
+		return err == nil
+	})
+	if first < 0 {
+		return nil, errors.New("invocation header has no numbered minute columns")
+	}
+	counts := make([]uint64, len(header)-first)
+	for line := 2; ; line++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("invocation line %d has %d columns, want %d", line, len(record), len(header))
+		}
+		for i, field := range record[first:] {
+			count, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invocation line %d: %w", line, err)
+			}
+			counts[i] += count
+		}
+	}
+}
+
+// CountPhases turns per-interval request counts, such as from
+// ReadInvocationCounts, into back-to-back phases that replay their shape over
+// duration, each bin getting an equal share. Rates are scaled so the busiest
+// bin offers peakRPS, rounded but never to zero; empty bins become idle
+// phases. Every phase with traffic is sent to targets. A day of minutes can
+// thus be compressed into an hour at a rate the target can take. Duration
+// must leave every bin at least a nanosecond.
+func CountPhases(counts []uint64, duration time.Duration, peakRPS uint64, targets ...Target) ([]Phase, error) {
+	switch {
+	case len(counts) == 0:
+		return nil, errors.New("counts must not be empty")
+	case duration <= 0:
+		return nil, errors.New("count duration must be positive")
+	case duration < time.Duration(len(counts)):
+		return nil, fmt.Errorf("count duration %v is too short for %d intervals", duration, len(counts))
+	case peakRPS == 0:
+		return nil, errors.New("peak rate must be positive")
+	}
+	peak := slices.Max(counts)
+	phases := make([]Phase, len(counts))
+	for i, count := range counts {
+		start := duration * time.Duration(i) / time.Duration(len(counts))
+		end := duration * time.Duration(i+1) / time.Duration(len(counts))
+		phases[i] = Phase{StartAt: start, Duration: end - start}
+		if count == 0 {
+			phases[i].Idle = true
+			continue
+		}
+		phases[i].RPS = max(1, uint64(math.Round(float64(count)/float64(peak)*float64(peakRPS))))
+		phases[i].Targets = slices.Clone(targets)
+	}
+	return phases, nil
+}
+
+// TracePhases bins a trace into back-to-back phases of interval, each offering
+// the trace's average rate over its interval, so recorded traffic can be
+// replayed as a rate shape, scaled with SetRate, or edited. Rates are rounded
+// to whole requests per second but never to zero; intervals without requests
+// become idle phases. Every phase with traffic is sent to targets. Offsets
+// must not be negative.
+func TracePhases(trace []time.Duration, interval time.Duration, targets ...Target) ([]Phase, error) {
+	if interval <= 0 {
+		return nil, errors.New("trace interval must be positive")
+	}
+	if len(trace) == 0 {
+		return nil, nil
+	}
+	if i := slices.IndexFunc(trace, func(offset time.Duration) bool { return offset < 0 }); i >= 0 {
+		return nil, fmt.Errorf("trace offset %d is negative", i)
+	}
+	counts := make([]uint64, int(slices.Max(trace)/interval)+1)
+	for _, offset := range trace {
+		counts[offset/interval]++
+	}
+	phases := make([]Phase, len(counts))
+	for i, count := range counts {
+		phases[i] = Phase{StartAt: time.Duration(i) * interval, Duration: interval}
+		if count == 0 {
+			phases[i].Idle = true
+			continue
+		}
+		phases[i].RPS = max(1, uint64(math.Round(float64(count)/interval.Seconds())))
+		phases[i].Targets = slices.Clone(targets)
+	}
+	return phases, nil
+}
@@ -0,0 +1,216 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurstsThenPaces(t *testing.T) {
+	bucket := NewTokenBucket(100, 5)
+	first, ok := bucket.Next()
+	if !ok || first.At != 0 || first.Count != 5 {
+		t.Fatalf("first batch=%+v, want a burst of 5 at the phase start", first)
+	}
+	for i := 1; i <= 3; i++ {
+		batch, ok := bucket.Next()
+		if !ok || batch.At != time.Duration(i)*10*time.Millisecond || batch.Count != 1 {
+			t.Fatalf("batch %d=%+v, want one arrival every 10ms", i, batch)
+		}
+	}
+}
+
+func TestTokenBucketFollowsSpecResolution(t *testing.T) {
+	shared := NewTokenBucket(100, 0)
+	workload := mustWorkload(t, Spec{
+		Duration:   time.Second,
+		Resolution: 50 * time.Millisecond,
+		Endpoints:  map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:     []Phase{{Duration: time.Second, Pacer: func() Pacer { return shared }, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	pacer := workload.phases[0].newPacer()
+	if pacer == shared || shared.(*tokenBucket).resolution != 0 {
+		t.Fatal("expected the phase to leave the factory's bucket untouched")
+	}
+	for i := 1; i <= 3; i++ {
+		batch, ok := pacer.Next()
+		if !ok || batch.At != time.Duration(i)*50*time.Millisecond || batch.Count != 5 {
+			t.Fatalf("batch %d=%+v, want five arrivals every 50ms", i, batch)
+		}
+	}
+}
+
+func TestCustomPacerIsStoppedAtPhaseEnd(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases: []Phase{{
+			Duration: 30 * time.Millisecond,
+			Pacer:    func() Pacer { return NewTokenBucket(100, 3) },
+			Targets:  []Target{{Endpoint: "one", Weight: 1}},
+		}},
+	})
+	report := mustRun(t, workload)
+	if report.Scheduled != 6 || endpoint.count.Load() != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d, want a burst of 3 and three paced arrivals", report.Scheduled, report.Issued)
+	}
+	if err := workload.SetRate(0, 10); err == nil {
+		t.Fatal("expected custom pacer rate adjustment to be rejected")
+	}
+}
+
+func TestNilPacerFailsRun(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases: []Phase{
+			{Duration: 10 * time.Millisecond, Pacer: func() Pacer { return nil }, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Duration: 10 * time.Millisecond, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+	report, err := workload.Run(context.Background())
+	if err == nil || report.Phases[0].Scheduled != 0 || report.Phases[1].Scheduled == 0 {
+		t.Fatalf("err=%v phases=%+v, want the nil pacer reported and other phases run", err, report.Phases)
+	}
+}
+
+func TestRegisteredPacerIsSelectedByName(t *testing.T) {
+	err := RegisterPacer("test-bucket", func(phase Phase) (func() Pacer, error) {
+		if phase.RPS == 0 {
+			return nil, errors.New("test-bucket requires RPS")
+		}
+		return func() Pacer { return NewTokenBucket(phase.RPS, 2) }, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterPacer("test-bucket", func(Phase) (func() Pacer, error) { return nil, nil }); err == nil {
+		t.Fatal("expected duplicate pacer registration to be rejected")
+	}
+
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases:    []Phase{{Duration: 20 * time.Millisecond, RPS: 100, Schedule: "test-bucket", Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	if report := mustRun(t, workload); report.Scheduled != 4 {
+		t.Fatalf("scheduled=%d, want a burst of 2 and two paced arrivals", report.Scheduled)
+	}
+
+	for _, phase := range []Phase{
+		{Duration: time.Second, Schedule: "test-bucket", Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		{Duration: time.Second, RPS: 1, Schedule: "missing", Targets: []Target{{Endpoint: "one", Weight: 1}}},
+	} {
+		if _, err := NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: []Phase{phase}}); err == nil {
+			t.Fatalf("expected schedule %q with RPS %d to be rejected", phase.Schedule, phase.RPS)
+		}
+	}
+}
+
+func TestReadRequestLogParsesAccessLogsAndCSV(t *testing.T) {
+	accessLog := `10.0.0.1 - - [10/Oct/2026:13:55:37 +0000] "GET /a HTTP/1.1" 200 12
+10.0.0.2 - - [10/Oct/2026:13:55:36 +0000] "GET /b HTTP/1.1" 200 12
+
+10.0.0.1 - - [10/Oct/2026:15:55:39 +0200] "GET /c HTTP/1.1" 500 0 "-" "curl/8.0"
+`
+	trace, err := ReadRequestLog(strings.NewReader(accessLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{0, time.Second, 3 * time.Second}; !slices.Equal(trace, want) {
+		t.Fatalf("trace=%v, want %v", trace, want)
+	}
+
+	csv := "timestamp,path\n1791640536.5,/a\n2026-10-10T13:55:36Z,/b\n\"1791640538\",/c\n"
+	trace, err = ReadRequestLog(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{0, 500 * time.Millisecond, 2 * time.Second}; !slices.Equal(trace, want) {
+		t.Fatalf("trace=%v, want %v", trace, want)
+	}
+
+	if _, err := ReadRequestLog(strings.NewReader("1760104536\nnot a time\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err=%v, want the malformed line reported", err)
+	}
+}
+
+func TestTracePhasesBinsRates(t *testing.T) {
+	trace := []time.Duration{0, 100 * time.Millisecond, 900 * time.Millisecond, 2500 * time.Millisecond}
+	targets := []Target{{Endpoint: "one", Weight: 1}}
+	phases, err := TracePhases(trace, time.Second, targets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != 3 {
+		t.Fatalf("phases=%+v, want three one-second bins", phases)
+	}
+	if phases[0].RPS != 3 || phases[0].StartAt != 0 || len(phases[0].Targets) != 1 {
+		t.Fatalf("first phase=%+v, want 3 rps from the start", phases[0])
+	}
+	if !phases[1].Idle || phases[1].StartAt != time.Second {
+		t.Fatalf("second phase=%+v, want an idle gap", phases[1])
+	}
+	if phases[2].RPS != 1 || phases[2].StartAt != 2*time.Second {
+		t.Fatalf("third phase=%+v, want 1 rps", phases[2])
+	}
+	workload := mustWorkload(t, Spec{
+		Duration:  3 * time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    phases,
+	})
+	if plan := workload.Plan(); plan.Expected != 4 {
+		t.Fatalf("expected=%d, want the trace's 4 requests", plan.Expected)
+	}
+	if _, err := TracePhases([]time.Duration{time.Second, -time.Second}, time.Second); err == nil {
+		t.Fatal("expected a negative offset to be rejected")
+	}
+}
+
+func TestCountPhasesScalesInvocationTrace(t *testing.T) {
+	trace := "HashOwner,HashApp,HashFunction,Trigger,1,2,3,4\n" +
+		"o1,a1,f1,http,10,0,40,5\n" +
+		"o1,a1,f2,timer,10,0,60,0\n"
+	counts, err := ReadInvocationCounts(strings.NewReader(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(counts, []uint64{20, 0, 100, 5}) {
+		t.Fatalf("counts=%v, want per-minute totals", counts)
+	}
+	phases, err := CountPhases(counts, 2*time.Second, 50, Target{Endpoint: "one", Weight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != 4 || phases[2].RPS != 50 || phases[0].RPS != 10 || !phases[1].Idle || phases[3].RPS != 3 {
+		t.Fatalf("phases=%+v, want rates scaled to the peak", phases)
+	}
+	if phases[3].StartAt != 1500*time.Millisecond || phases[3].Duration != 500*time.Millisecond {
+		t.Fatalf("last phase=%+v, want bins sharing the duration", phases[3])
+	}
+	mustWorkload(t, Spec{Duration: 2 * time.Second, Endpoints: map[string]Endpoint{"one": &countingEndpoint{}}, Phases: phases})
+
+	for _, invalid := range []struct {
+		counts   []uint64
+		duration time.Duration
+		peak     uint64
+	}{
+		{nil, time.Second, 50},
+		{counts, 0, 50},
+		{counts, 3, 50},
+		{counts, time.Second, 0},
+	} {
+		if _, err := CountPhases(invalid.counts, invalid.duration, invalid.peak); err == nil {
+			t.Fatalf("expected counts=%v duration=%v peak=%d to be rejected", invalid.counts, invalid.duration, invalid.peak)
+		}
+	}
+
+	if _, err := ReadInvocationCounts(strings.NewReader("HashFunction,1\nf1,x\n")); err == nil {
+		t.Fatal("expected a non-numeric count to be rejected")
+	}
+}
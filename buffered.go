@@ -0,0 +1,125 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// BufferedCollector decouples a slow collector from request goroutines: results
+// are queued on a channel and handed to the wrapped collector by one writer
+// goroutine, so a slow disk or network sink never holds up dispatch. The
+// wrapped collector's CollectContext is used when it implements ContextCollector.
+type BufferedCollector[R any] struct {
+	collector        Collector[R]
+	contextCollector ContextCollector[R]
+	whenFull         FullPolicy
+	results          chan bufferedResult[R]
+	collectMu        sync.RWMutex
+	closed           bool
+	done             chan struct{}
+	closeOnce        sync.Once
+	dropped          atomic.Uint64
+}
+
+type bufferedResult[R any] struct {
+	ctx    context.Context
+	result R
+}
+
+// NewBufferedCollector wraps collector with a queue of capacity results.
+// whenFull decides what Collect does while the queue is full: DelayWhenFull
+// waits for space, while DropWhenFull discards the result and counts it in
+// Dropped, so a stalled sink cannot slow the workload at all.
+func NewBufferedCollector[R any](collector Collector[R], capacity int, whenFull FullPolicy) (*BufferedCollector[R], error) {
+	if isNil(collector) {
+		return nil, errors.New("collector must be non-nil")
+	}
+	if capacity <= 0 {
+		return nil, errors.New("buffer capacity must be positive")
+	}
+	if whenFull > DelayWhenFull {
+		return nil, errors.New("unknown full policy")
+	}
+	contextCollector, _ := collector.(ContextCollector[R])
+	c := &BufferedCollector[R]{
+		collector:        collector,
+		contextCollector: contextCollector,
+		whenFull:         whenFull,
+		results:          make(chan bufferedResult[R], capacity),
+		done:             make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+// Collect queues a result.
+func (c *BufferedCollector[R]) Collect(result R) {
+	c.enqueue(bufferedResult[R]{result: result})
+}
+
+// CollectContext queues a result with the values of its request context. The
+// context is only kept when the wrapped collector uses it.
+func (c *BufferedCollector[R]) CollectContext(ctx context.Context, result R) {
+	if c.contextCollector == nil {
+		c.Collect(result)
+		return
+	}
+	c.enqueue(bufferedResult[R]{ctx: context.WithoutCancel(ctx), result: result})
+}
+
+func (c *BufferedCollector[R]) enqueue(queued bufferedResult[R]) {
+	c.collectMu.RLock()
+	defer c.collectMu.RUnlock()
+	if c.closed {
+		c.dropped.Add(1)
+		return
+	}
+	if c.whenFull == DelayWhenFull {
+		c.results <- queued
+		return
+	}
+	select {
+	case c.results <- queued:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of results discarded because the queue was full
+// or the collector was closed.
+func (c *BufferedCollector[R]) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Close hands every queued result to the wrapped collector, then closes it.
+func (c *BufferedCollector[R]) Close() {
+	c.collectMu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.results)
+	}
+	c.collectMu.Unlock()
+	<-c.done
+	c.closeOnce.Do(c.collector.Close)
+}
+
+// Err returns the wrapped collector's error when it is an ErrorReporter.
+func (c *BufferedCollector[R]) Err() error {
+	if reporter, ok := c.collector.(ErrorReporter); ok {
+		return reporter.Err()
+	}
+	return nil
+}
+
+func (c *BufferedCollector[R]) run() {
+	defer close(c.done)
+	for queued := range c.results {
+		if queued.ctx != nil && c.contextCollector != nil {
+			c.contextCollector.CollectContext(queued.ctx, queued.result)
+		} else {
+			c.collector.Collect(queued.result)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package go_loadgen
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type gatedCollector struct {
+	gate      chan struct{}
+	collected atomic.Uint64
+	tagged    atomic.Uint64
+}
+
+func (c *gatedCollector) Collect(testResult) {
+	<-c.gate
+	c.collected.Add(1)
+}
+
+func (c *gatedCollector) Close() {}
+
+func (c *gatedCollector) CollectContext(ctx context.Context, result testResult) {
+	if info, ok := PhaseFromContext(ctx); ok && info.Tags["variant"] == "a" {
+		c.tagged.Add(1)
+	}
+	c.Collect(result)
+}
+
+func TestBufferedCollectorDropsWhileSinkStalls(t *testing.T) {
+	inner := &gatedCollector{gate: make(chan struct{})}
+	collector, err := NewBufferedCollector[testResult](inner, 2, DropWhenFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	for range 10 {
+		collector.Collect(testResult{})
+	}
+	if elapsed := time.Since(started); elapsed > 50*time.Millisecond {
+		t.Fatalf("collect took %s with a stalled sink, want it never to block", elapsed)
+	}
+	close(inner.gate)
+	collector.Close()
+	if dropped := collector.Dropped(); dropped < 7 || inner.collected.Load() != 10-dropped {
+		t.Fatalf("dropped=%d collected=%d, want everything beyond the queue dropped and the rest collected", dropped, inner.collected.Load())
+	}
+	collector.Collect(testResult{})
+	if collector.Dropped() == 0 || inner.collected.Load()+collector.Dropped() != 11 {
+		t.Fatal("expected results after Close to be dropped")
+	}
+}
+
+func TestBufferedCollectorDelaysAndKeepsPhaseTags(t *testing.T) {
+	inner := &gatedCollector{gate: make(chan struct{})}
+	close(inner.gate)
+	collector, err := NewBufferedCollector[testResult](inner, 1, DelayWhenFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, testClient(func(context.Context, testRequest) testResult { return testResult{} }), testProvider{}, collector)},
+		Phases:    []Phase{{Tags: map[string]string{"variant": "a"}, Duration: 20 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	collector.Close()
+	if inner.collected.Load() != report.Completed || inner.tagged.Load() != report.Completed || collector.Dropped() != 0 {
+		t.Fatalf("collected=%d tagged=%d dropped=%d completed=%d", inner.collected.Load(), inner.tagged.Load(), collector.Dropped(), report.Completed)
+	}
+	if _, err := NewBufferedCollector[testResult](inner, 0, DropWhenFull); err == nil {
+		t.Fatal("expected a zero capacity to be rejected")
+	}
+}
+
+func TestBufferedCollectorSkipsContextForPlainCollectors(t *testing.T) {
+	inner := &testCollector{}
+	collector, err := NewBufferedCollector[testResult](inner, 1, DropWhenFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer collector.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if allocs := testing.AllocsPerRun(100, func() { collector.CollectContext(ctx, testResult{}) }); allocs != 0 {
+		t.Fatalf("allocs=%v, want the unused context not kept", allocs)
+	}
+}
@@ -0,0 +1,50 @@
+package go_loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// Checkpoint is a position in a workload's schedule. Schedules are derived from
+// the workload's seed, so the offset is enough to continue an interrupted run:
+// pacers, including seeded Poisson arrivals, and endpoint choices replay to the
+// offset without sending traffic. Virtual users restart their own sequences.
+type Checkpoint struct {
+	// Seed and Phases identify the workload the checkpoint belongs to.
+	Seed   uint64        `json:"seed"`
+	Phases int           `json:"phases"`
+	Offset time.Duration `json:"offset"`
+}
+
+// WriteTo writes the checkpoint as JSON.
+func (c Checkpoint) WriteTo(writer io.Writer) (int64, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return 0, err
+	}
+	n, err := writer.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// ReadCheckpoint reads a checkpoint written by Checkpoint.WriteTo.
+func ReadCheckpoint(reader io.Reader) (Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := json.NewDecoder(reader).Decode(&checkpoint)
+	return checkpoint, err
+}
+
+// RunFrom continues a run from checkpoint, typically Report.Checkpoint of an
+// interrupted run. Phases that ended before it are skipped and the phase in
+// progress continues where it stopped. The report covers only the resumed part.
+func (w *Workload) RunFrom(ctx context.Context, checkpoint Checkpoint) (Report, error) {
+	if checkpoint.Seed != w.seed || checkpoint.Phases != len(w.phases) {
+		return Report{}, errors.New("checkpoint belongs to a different workload")
+	}
+	if checkpoint.Offset < 0 || checkpoint.Offset > w.duration {
+		return Report{}, errors.New("checkpoint offset is outside the workload")
+	}
+	return w.runFrom(ctx, checkpoint.Offset, nil)
+}
@@ -0,0 +1,54 @@
+package go_loadgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunFromCheckpointCompletesInterruptedRun(t *testing.T) {
+	spec := Spec{
+		Duration:  100 * time.Millisecond,
+		Seed:      7,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases: []Phase{
+			{Duration: 50 * time.Millisecond, RPS: 200, Arrivals: PoissonArrivals, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{StartAt: 50 * time.Millisecond, Duration: 50 * time.Millisecond, RPS: 200, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	}
+	workload := mustWorkload(t, spec)
+	want := workload.Plan().Expected
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(30*time.Millisecond, cancel)
+	first, err := workload.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want the first run interrupted", err)
+	}
+	var saved bytes.Buffer
+	if _, err := first.Checkpoint.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := ReadCheckpoint(&saved)
+	if err != nil || checkpoint != first.Checkpoint || checkpoint.Offset < 20*time.Millisecond || checkpoint.Offset > 50*time.Millisecond {
+		t.Fatalf("checkpoint=%+v err=%v, want the interrupted offset", checkpoint, err)
+	}
+
+	second, err := mustWorkload(t, spec).RunFrom(context.Background(), checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Scheduled+second.Scheduled != want || second.Phases[1].Scheduled != 10 {
+		t.Fatalf("scheduled %d then %d, want %d in total", first.Scheduled, second.Scheduled, want)
+	}
+	if second.Checkpoint.Offset != spec.Duration {
+		t.Fatalf("checkpoint=%+v, want the completed workload", second.Checkpoint)
+	}
+
+	spec.Seed++
+	if _, err := mustWorkload(t, spec).RunFrom(context.Background(), checkpoint); err == nil {
+		t.Fatal("expected a checkpoint of another workload to be rejected")
+	}
+}
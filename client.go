@@ -0,0 +1,211 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ClientFunc adapts a function to a Client.
+type ClientFunc[C any, R any] func(context.Context, C) R
+
+// CallEndpoint implements Client.
+func (f ClientFunc[C, R]) CallEndpoint(ctx context.Context, request C) R { return f(ctx, request) }
+
+// ClientMiddleware layers a cross-cutting concern such as auth headers,
+// logging, metrics, or fault injection around a client without modifying it.
+type ClientMiddleware[C any, R any] func(Client[C, R]) Client[C, R]
+
+// Chain wraps client in middleware. The first middleware is the outermost, so
+// it sees each request first and each result last.
+func Chain[C any, R any](client Client[C, R], middleware ...ClientMiddleware[C, R]) Client[C, R] {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		client = middleware[i](client)
+	}
+	return client
+}
+
+// RetryPolicy decides how RetryingClient repeats failed calls.
+type RetryPolicy[R any] struct {
+	// MaxAttempts bounds the calls per request, including the first.
+	MaxAttempts int
+	// Backoff returns the wait before retry attempt, starting at 1. Nil
+	// retries immediately; see ExponentialBackoff.
+	Backoff func(attempt int) time.Duration
+	// Retry reports whether a result is eligible for another attempt. Nil
+	// retries results whose Outcome reports failure.
+	Retry func(R) bool
+}
+
+// Retried is a result of RetryingClient: the final attempt's result with the
+// number of attempts and the latency of all of them, so retry-induced latency
+// stays visible to collectors. It reports the final result's Outcome.
+type Retried[R any] struct {
+	Result   R
+	Attempts int
+	Latency  time.Duration
+}
+
+// Failed implements Outcome.
+func (r Retried[R]) Failed() bool { return failed(r.Result) }
+
+// RetryingClient decorates a client with retries. Retries happen within the
+// original arrival, so they add latency rather than load.
+type RetryingClient[C any, R any] struct {
+	client Client[C, R]
+	policy RetryPolicy[R]
+}
+
+// NewRetryingClient wraps client with policy.
+func NewRetryingClient[C any, R any](client Client[C, R], policy RetryPolicy[R]) (*RetryingClient[C, R], error) {
+	if isNil(client) {
+		return nil, errors.New("client must be non-nil")
+	}
+	if policy.MaxAttempts < 1 {
+		return nil, errors.New("retry policy must allow at least one attempt")
+	}
+	if policy.Retry == nil {
+		policy.Retry = failed[R]
+	}
+	return &RetryingClient[C, R]{client: client, policy: policy}, nil
+}
+
+// CallEndpoint implements Client. Retries stop early when ctx is done.
+func (c *RetryingClient[C, R]) CallEndpoint(ctx context.Context, request C) Retried[R] {
+	started := time.Now()
+	result := Retried[R]{Result: c.client.CallEndpoint(ctx, request), Attempts: 1}
+	for result.Attempts < c.policy.MaxAttempts && c.policy.Retry(result.Result) {
+		if c.policy.Backoff != nil {
+			if !sleep(ctx, c.policy.Backoff(result.Attempts)) {
+				break
+			}
+		} else if ctx.Err() != nil {
+			break
+		}
+		result.Result = c.client.CallEndpoint(ctx, request)
+		result.Attempts++
+	}
+	result.Latency = time.Since(started)
+	return result
+}
+
+// ExponentialBackoff returns a backoff that doubles from base up to limit.
+func ExponentialBackoff(base, limit time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		wait := base
+		for range attempt - 1 {
+			if wait >= limit/2 {
+				return limit
+			}
+			wait *= 2
+		}
+		return min(wait, limit)
+	}
+}
+
+// BreakerPolicy configures CircuitBreakerClient.
+type BreakerPolicy[R any] struct {
+	// Failures is the number of consecutive failed results that opens the circuit.
+	Failures int
+	// Cooldown is how long the circuit stays open before a probe request is
+	// let through. A successful probe closes it; a failed one reopens it.
+	Cooldown time.Duration
+	// Failed reports whether a result counts as a failure. Nil uses the
+	// result's Outcome.
+	Failed func(R) bool
+}
+
+// Guarded is a result of CircuitBreakerClient. Rejected requests were
+// short-circuited by an open circuit without reaching the target; they have a
+// zero Result and report failure.
+type Guarded[R any] struct {
+	Result   R
+	Rejected bool
+}
+
+// Failed implements Outcome.
+func (g Guarded[R]) Failed() bool { return g.Rejected || failed(g.Result) }
+
+// CircuitBreakerClient decorates a client with a circuit breaker, modelling
+// resilient clients and shedding load from a failing target.
+type CircuitBreakerClient[C any, R any] struct {
+	client Client[C, R]
+	policy BreakerPolicy[R]
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+// NewCircuitBreakerClient wraps client with policy.
+func NewCircuitBreakerClient[C any, R any](client Client[C, R], policy BreakerPolicy[R]) (*CircuitBreakerClient[C, R], error) {
+	if isNil(client) {
+		return nil, errors.New("client must be non-nil")
+	}
+	if policy.Failures < 1 || policy.Cooldown <= 0 {
+		return nil, errors.New("breaker policy needs a positive failure count and cooldown")
+	}
+	if policy.Failed == nil {
+		policy.Failed = failed[R]
+	}
+	return &CircuitBreakerClient[C, R]{client: client, policy: policy}, nil
+}
+
+// CallEndpoint implements Client.
+func (c *CircuitBreakerClient[C, R]) CallEndpoint(ctx context.Context, request C) Guarded[R] {
+	if !c.allow() {
+		return Guarded[R]{Rejected: true}
+	}
+	result := c.client.CallEndpoint(ctx, request)
+	c.record(c.policy.Failed(result))
+	return Guarded[R]{Result: result}
+}
+
+// allow reports whether a request may pass, admitting a single probe once an
+// open circuit has cooled down.
+func (c *CircuitBreakerClient[C, R]) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.open {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < c.policy.Cooldown {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+func (c *CircuitBreakerClient[C, R]) record(failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !failed {
+		c.failures, c.open, c.probing = 0, false, false
+		return
+	}
+	c.failures++
+	if c.probing || c.failures >= c.policy.Failures {
+		c.open, c.probing, c.openedAt = true, false, time.Now()
+	}
+}
+
+// failed reports whether a result's Outcome reports failure.
+func failed[R any](result R) bool {
+	outcome, ok := any(result).(Outcome)
+	return ok && outcome.Failed()
+}
+
+// sleep waits for d unless ctx ends first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,106 @@
+package go_loadgen
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryingClientRetriesFailures(t *testing.T) {
+	var calls atomic.Int64
+	flaky := testClient(func(context.Context, testRequest) testResult {
+		return testResult{failed: calls.Add(1) < 3}
+	})
+	client, err := NewRetryingClient[testRequest, testResult](flaky, RetryPolicy[testResult]{MaxAttempts: 5, Backoff: ExponentialBackoff(time.Millisecond, 2*time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := client.CallEndpoint(context.Background(), testRequest{})
+	if result.Failed() || result.Attempts != 3 || result.Latency < 3*time.Millisecond {
+		t.Fatalf("result=%+v, want success on the third attempt after backing off", result)
+	}
+
+	calls.Store(-10)
+	if result := client.CallEndpoint(context.Background(), testRequest{}); !result.Failed() || result.Attempts != 5 {
+		t.Fatalf("result=%+v, want failure after five attempts", result)
+	}
+	if _, err := NewRetryingClient[testRequest, testResult](flaky, RetryPolicy[testResult]{}); err == nil {
+		t.Fatal("expected a policy without attempts to be rejected")
+	}
+}
+
+func TestExponentialBackoffIsCapped(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := backoff(attempt + 1); got != want*time.Millisecond {
+			t.Fatalf("attempt %d waited %s, want %s", attempt+1, got, want*time.Millisecond)
+		}
+	}
+	if got := ExponentialBackoff(time.Second, time.Minute)(100); got != time.Minute {
+		t.Fatalf("attempt 100 waited %s, want the cap", got)
+	}
+}
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int64
+	target := testClient(func(context.Context, testRequest) testResult {
+		calls.Add(1)
+		return testResult{failed: !healthy.Load()}
+	})
+	client, err := NewCircuitBreakerClient[testRequest, testResult](target, BreakerPolicy[testResult]{Failures: 3, Cooldown: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for range 10 {
+		client.CallEndpoint(ctx, testRequest{})
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("target received %d calls, want the circuit open after 3 failures", calls.Load())
+	}
+	if result := client.CallEndpoint(ctx, testRequest{}); !result.Rejected || !result.Failed() {
+		t.Fatalf("result=%+v, want a rejected failure", result)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if result := client.CallEndpoint(ctx, testRequest{}); result.Rejected || calls.Load() != 4 {
+		t.Fatalf("result=%+v calls=%d, want a failed probe after the cooldown", result, calls.Load())
+	}
+	if result := client.CallEndpoint(ctx, testRequest{}); !result.Rejected {
+		t.Fatal("expected a failed probe to reopen the circuit")
+	}
+
+	healthy.Store(true)
+	time.Sleep(25 * time.Millisecond)
+	for range 5 {
+		if result := client.CallEndpoint(ctx, testRequest{}); result.Failed() {
+			t.Fatalf("result=%+v, want the circuit closed after a successful probe", result)
+		}
+	}
+}
+
+func TestChainAppliesMiddlewareOutermostFirst(t *testing.T) {
+	var order []string
+	trace := func(name string) ClientMiddleware[testRequest, testResult] {
+		return func(next Client[testRequest, testResult]) Client[testRequest, testResult] {
+			return ClientFunc[testRequest, testResult](func(ctx context.Context, request testRequest) testResult {
+				order = append(order, name+" in")
+				result := next.CallEndpoint(ctx, request)
+				order = append(order, name+" out")
+				return result
+			})
+		}
+	}
+	client := Chain[testRequest, testResult](testClient(func(context.Context, testRequest) testResult {
+		order = append(order, "client")
+		return testResult{}
+	}), trace("auth"), trace("metrics"))
+
+	client.CallEndpoint(context.Background(), testRequest{})
+	if want := "[auth in metrics in client metrics out auth out]"; fmt.Sprint(order) != want {
+		t.Fatalf("order=%v, want %s", order, want)
+	}
+}
@@ -0,0 +1,205 @@
+package go_loadgen
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock is the time source a workload schedules against. The default is the
+// system clock; tests can substitute a FakeClock to run schedules, drains, and
+// background checks without waiting in real time. Request latencies and
+// RequestTimeout deadlines are always measured in real time.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, f func()) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a Clock's time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// Ticker is a Clock's time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type systemClock struct{}
+
+type systemTimer struct{ *time.Timer }
+
+type systemTicker struct{ *time.Ticker }
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// FakeClock is a Clock that only moves when advanced. Timers and tickers fire
+// in deadline order as Advance passes them. A schedule sees each Advance as a
+// jump, so batches whose deadline it skips are reported as missed; advance in
+// steps that land on the arrivals under test.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{}
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer that fires once the clock has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc calls f in its own goroutine once the clock has advanced by d.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker returns a ticker that fires every time the clock advances by d.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	t := &fakeTicker{&fakeTimer{clock: c, c: make(chan time.Time, 1), period: d}}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker whose
+// deadline it passes.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for len(c.timers) != 0 {
+		next := slices.MinFunc(c.timers, func(a, b *fakeTimer) int { return a.at.Compare(b.at) })
+		if next.at.After(end) {
+			break
+		}
+		c.now = next.at
+		next.fire()
+	}
+	c.now = end
+}
+
+// BlockUntilContext waits until at least n timers and tickers are pending, so a
+// test advances the clock only once the workload is waiting on it.
+func (c *FakeClock) BlockUntilContext(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// schedule adds or removes t from the pending timers. c.mu must be held.
+func (c *FakeClock) schedule(t *fakeTimer, at time.Time, pending bool) bool {
+	i := slices.Index(c.timers, t)
+	if i >= 0 {
+		c.timers = slices.Delete(c.timers, i, i+1)
+	}
+	if pending {
+		t.at = at
+		c.timers = append(c.timers, t)
+		close(c.changed)
+		c.changed = make(chan struct{})
+	}
+	return i >= 0
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	f      func()
+	at     time.Time
+	period time.Duration
+}
+
+type fakeTicker struct{ *fakeTimer }
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	return t.clock.schedule(t, t.clock.now.Add(d), true)
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	return t.clock.schedule(t, time.Time{}, false)
+}
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
+
+// drain discards a stale tick, as time.Timer does since Go 1.23.
+func (t *fakeTimer) drain() {
+	if t.c != nil {
+		select {
+		case <-t.c:
+		default:
+		}
+	}
+}
+
+// fire delivers the timer's tick and reschedules tickers. t.clock.mu must be held.
+func (t *fakeTimer) fire() {
+	now := t.clock.now
+	if t.period > 0 {
+		t.clock.schedule(t, now.Add(t.period), true)
+	} else {
+		t.clock.schedule(t, time.Time{}, false)
+	}
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
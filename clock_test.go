@@ -0,0 +1,70 @@
+package go_loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFakeClockFiresTimersInDeadlineOrder(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	ticker := clock.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timer := clock.NewTimer(25 * time.Millisecond)
+	fired := make(chan time.Time, 1)
+	clock.AfterFunc(15*time.Millisecond, func() { fired <- clock.Now() })
+
+	clock.Advance(20 * time.Millisecond)
+	if got := <-ticker.C(); got != time.Unix(0, 0).Add(10*time.Millisecond) {
+		t.Fatalf("tick=%s, want the first tick kept while the channel was full", got)
+	}
+	if got := <-fired; got.Before(time.Unix(0, 0).Add(15 * time.Millisecond)) {
+		t.Fatalf("func ran at %s, want after its deadline", got)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+	if !timer.Stop() || timer.Stop() {
+		t.Fatal("want only the first Stop to report a pending timer")
+	}
+	clock.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeClockRunsScheduleWithoutWaiting(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Hour,
+		Clock:     clock,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases:    []Phase{{Duration: time.Hour, RPS: 2, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	ctx, finished := context.WithCancel(context.Background())
+	var report Report
+	var err error
+	go func() {
+		defer finished()
+		report, err = workload.Run(context.Background())
+	}()
+	started := time.Now()
+	for clock.BlockUntilContext(ctx, 1) == nil {
+		clock.Advance(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := workload.Plan().Expected; report.Scheduled != want || report.Missed != 0 || endpoint.count.Load() != want {
+		t.Fatalf("report=%+v, want all %d arrivals of the hour issued", report.Counts, want)
+	}
+	if report.SchedulingDuration < 59*time.Minute || time.Since(started) > 10*time.Second {
+		t.Fatalf("scheduled for %s in %s, want the hour on the fake clock to pass quickly", report.SchedulingDuration, time.Since(started))
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,34 +25,119 @@ type CSVSerializable interface {
 	CSVRecord() []string
 }
 
-// CSVCollector can collect results and write them to a CSV file. It requires result types to implement CSVSerializable. It will write the headers on the first collect and then every flushInterval. Note that headers will be rewritten if a new collector is created.
+// CSVCollector can collect results and write them to a CSV file. It requires result types to implement CSVSerializable. It will write the headers on the first collect and then every flushInterval. Note that a new collector overwrites the file unless WithCSVCollectorAppend is used.
 type CSVCollector[R CSVSerializable] struct {
 	writer        *csv.Writer
 	file          *os.File
+	closed        bool
 	flushInterval time.Duration
 	filePath      string
 	headerWritten bool
+	metadata      bool
+	appendMode    bool
+	tags          []string
+	headers       []string
+	maxBytes      int64
+	every         time.Duration
+	size          int64
+	rows          uint64
+	opened        time.Time
+	files         []string
+	uploader      Uploader
+	removeLocal   bool
+	uploads       sync.WaitGroup
 	mu            sync.Mutex
+	errMu         sync.Mutex
+	err           error
 	ctx           context.Context
 	cancel        context.CancelFunc
 }
 
+// CSVCollectorOption configures a CSVCollector.
+type CSVCollectorOption func(*csvCollectorConfig)
+
+type csvCollectorConfig struct {
+	metadata    bool
+	appendMode  bool
+	tags        []string
+	maxBytes    int64
+	every       time.Duration
+	uploader    Uploader
+	removeLocal bool
+}
+
+// WithCSVCollectorMetadata prepends timestamp, workload, phase, and worker
+// columns, filled from the request context, so results can be attributed
+// without threading that bookkeeping through the result type. The timestamp is
+// when the result was collected, in RFC 3339 format; see MetadataRecord.
+func WithCSVCollectorMetadata() CSVCollectorOption {
+	return func(cfg *csvCollectorConfig) {
+		cfg.metadata = true
+	}
+}
+
+// WithCSVCollectorAppend opens an existing file for appending instead of
+// truncating it, and skips the header when the file already has content, so
+// interrupted or multi-stage runs accumulate into one file. Results must have
+// the same columns as the rows already in the file.
+func WithCSVCollectorAppend() CSVCollectorOption {
+	return func(cfg *csvCollectorConfig) {
+		cfg.appendMode = true
+	}
+}
+
+// WithCSVCollectorTags appends a column per phase tag key after the result's
+// own columns, so results can be sliced by experiment variant. Results from
+// phases without a key have an empty value.
+func WithCSVCollectorTags(keys ...string) CSVCollectorOption {
+	return func(cfg *csvCollectorConfig) {
+		cfg.tags = append(cfg.tags, keys...)
+	}
+}
+
+// WithCSVCollectorRotation starts a new file once the current one holds about
+// maxBytes or was opened every ago, whichever comes first; zero disables
+// either limit. Files are named after the collector's path with the time they
+// were opened, such as results-20060102T150405.000000000Z.csv, and each starts
+// with the header, so finished files can be shipped off the box mid-run.
+func WithCSVCollectorRotation(maxBytes int64, every time.Duration) CSVCollectorOption {
+	return func(cfg *csvCollectorConfig) {
+		cfg.maxBytes, cfg.every = max(maxBytes, 0), max(every, 0)
+	}
+}
+
+// WithCSVCollectorUpload uploads every finished file, on rotation and on
+// Close, and removes the local copy once stored if removeLocal is set. Close
+// waits for outstanding uploads.
+func WithCSVCollectorUpload(uploader Uploader, removeLocal bool) CSVCollectorOption {
+	return func(cfg *csvCollectorConfig) {
+		cfg.uploader, cfg.removeLocal = uploader, removeLocal
+	}
+}
+
 // NewCSVCollector creates a new CSV collector and starts a goroutine to flush the collector every flushInterval.
-func NewCSVCollector[R CSVSerializable](filePath string, flushInterval time.Duration) (*CSVCollector[R], error) {
+func NewCSVCollector[R CSVSerializable](filePath string, flushInterval time.Duration, opts ...CSVCollectorOption) (*CSVCollector[R], error) {
 	if flushInterval <= 0 {
 		return nil, fmt.Errorf("flush interval must be positive")
 	}
-	file, err := os.Create(filePath)
-	if err != nil {
-		return nil, err
+	var cfg csvCollectorConfig
+	for _, opt := range opts {
+		opt(&cfg)
 	}
-
 	c := &CSVCollector[R]{
-		writer:        csv.NewWriter(file),
-		file:          file,
 		flushInterval: flushInterval,
 		filePath:      filePath,
 		headerWritten: false,
+		metadata:      cfg.metadata,
+		appendMode:    cfg.appendMode,
+		tags:          cfg.tags,
+		maxBytes:      cfg.maxBytes,
+		every:         cfg.every,
+		uploader:      cfg.uploader,
+		removeLocal:   cfg.removeLocal,
+	}
+	if err := c.open(time.Now()); err != nil {
+		return nil, err
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	c.ctx, c.cancel = ctx, cancel
@@ -61,34 +149,235 @@ func NewCSVCollector[R CSVSerializable](filePath string, flushInterval time.Dura
 
 // Collect collects a result and writes it to the CSV file.
 func (c *CSVCollector[R]) Collect(result R) {
+	c.write(context.Background(), result)
+}
+
+// CollectContext collects a result with the metadata and tags of the phase
+// that issued it.
+func (c *CSVCollector[R]) CollectContext(ctx context.Context, result R) {
+	c.write(ctx, result)
+}
+
+// CollectBatch writes results under one lock. Endpoints use it instead of
+// CollectContext unless the collector writes metadata or tag columns.
+func (c *CSVCollector[R]) CollectBatch(results []R) {
+	now := time.Now()
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for _, result := range results {
+		c.writeLocked(context.Background(), now, result)
+	}
+}
 
+// needsContext reports whether records include columns from the request
+// context, which batching would lose.
+func (c *CSVCollector[R]) needsContext() bool {
+	return c.metadata || len(c.tags) != 0
+}
+
+func (c *CSVCollector[R]) write(ctx context.Context, result R) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeLocked(ctx, now, result)
+}
+
+// writeLocked writes one record. c.mu must be held.
+func (c *CSVCollector[R]) writeLocked(ctx context.Context, now time.Time, result R) {
+	if c.closed {
+		c.setErr(errCSVCollectorClosed)
+		return
+	}
+	if c.file == nil {
+		// A failed rotation already reported its error.
+		return
+	}
+	if c.full(now) {
+		c.rotate(now)
+	}
 	// Write header on first collect
+	if c.headers == nil {
+		var headers []string
+		if c.metadata {
+			headers = MetadataHeaders()
+		}
+		c.headers = append(append(headers, result.CSVHeaders()...), c.tags...)
+	}
 	if !c.headerWritten {
-		headers := result.CSVHeaders()
-		if err := c.writer.Write(headers); err != nil {
-			fmt.Printf("Error writing CSV header: %v\n", err)
+		if !c.writeHeader() {
 			return
 		}
-		c.headerWritten = true
 	}
 
-	record := result.CSVRecord()
+	var record []string
+	if c.metadata {
+		record = MetadataRecord(ctx, now)
+	}
+	record = append(record, result.CSVRecord()...)
+	info, _ := PhaseFromContext(ctx)
+	for _, key := range c.tags {
+		record = append(record, info.Tags[key])
+	}
 	if err := c.writer.Write(record); err != nil {
-		fmt.Printf("Error writing CSV record: %v\n", err)
+		c.setErr(fmt.Errorf("write CSV record: %w", err))
 	}
+	c.size += csvSize(record)
+	c.rows++
 }
 
-// Close flushes the CSV collector and closes the file.
-func (c *CSVCollector[R]) Close() {
+// MetadataHeaders returns the names of the metadata columns that collectors
+// prepend to each record: timestamp, workload, phase, and worker.
+func MetadataHeaders() []string {
+	return []string{"timestamp", "workload", "phase", "worker"}
+}
+
+// MetadataRecord returns the metadata columns for a result collected at now
+// from a request context. The phase and workload are the names from
+// PhaseFromContext and the worker is the number from WorkerFromContext;
+// values the context does not carry are empty.
+func MetadataRecord(ctx context.Context, now time.Time) []string {
+	info, _ := PhaseFromContext(ctx)
+	worker := ""
+	if id, ok := WorkerFromContext(ctx); ok {
+		worker = strconv.Itoa(id)
+	}
+	return []string{now.UTC().Format(time.RFC3339Nano), info.Workload, info.Name, worker}
+}
+
+// Files returns the paths written so far, oldest first. Without rotation it is
+// the collector's own path.
+func (c *CSVCollector[R]) Files() []string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return append([]string(nil), c.files...)
+}
 
-	c.cancel()
+// open starts a new file. c.mu must be held unless c is not yet shared.
+func (c *CSVCollector[R]) open(now time.Time) error {
+	path := c.filePath
+	if c.maxBytes > 0 || c.every > 0 {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-" + now.UTC().Format("20060102T150405.000000000Z") + ext
+	}
+	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if c.appendMode {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flag, 0o666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	c.file, c.writer, c.opened, c.size, c.rows = file, csv.NewWriter(file), now, info.Size(), 0
+	c.files = append(c.files, path)
+	// An appended file that already has content has its header.
+	c.headerWritten = c.size > 0
+	if c.headers != nil && !c.headerWritten {
+		c.writeHeader()
+	}
+	return nil
+}
+
+func (c *CSVCollector[R]) writeHeader() bool {
+	if err := c.writer.Write(c.headers); err != nil {
+		c.setErr(fmt.Errorf("write CSV header: %w", err))
+		return false
+	}
+	c.headerWritten = true
+	c.size += csvSize(c.headers)
+	return true
+}
+
+// full reports whether the current file reached a rotation limit. Files
+// without records are never rotated.
+func (c *CSVCollector[R]) full(now time.Time) bool {
+	return c.rows != 0 && ((c.maxBytes > 0 && c.size >= c.maxBytes) || (c.every > 0 && now.Sub(c.opened) >= c.every))
+}
+
+// rotate closes the current file and opens the next one. c.mu must be held.
+func (c *CSVCollector[R]) rotate(now time.Time) {
+	c.closeFile()
+	if err := c.open(now); err != nil {
+		c.setErr(fmt.Errorf("rotate CSV file: %w", err))
+	}
+}
+
+// finish uploads the file that was just closed. c.mu must be held.
+func (c *CSVCollector[R]) finish() {
+	if c.uploader == nil {
+		return
+	}
+	path := c.files[len(c.files)-1]
+	c.uploads.Go(func() {
+		c.setErr(upload(c.uploader, path, c.removeLocal))
+	})
+}
+
+// csvSize estimates the bytes a record takes, ignoring quoting.
+func csvSize(record []string) int64 {
+	size := int64(len(record))
+	for _, field := range record {
+		size += int64(len(field))
+	}
+	return size
+}
+
+// closeFile flushes and closes the current file and uploads it. c.mu must be
+// held.
+func (c *CSVCollector[R]) closeFile() {
 	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		c.setErr(fmt.Errorf("flush CSV file: %w", err))
+	}
+	if err := c.file.Close(); err != nil {
+		c.setErr(fmt.Errorf("close CSV file: %w", err))
+	}
+	c.file = nil
+	c.finish()
+}
+
+var errCSVCollectorClosed = errors.New("CSV collector is closed")
+
+// Close flushes the CSV collector, closes the file, and waits for uploads.
+// Results collected afterwards are dropped and reported by Err.
+func (c *CSVCollector[R]) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.cancel()
 	if c.file != nil {
-		c.file.Close()
+		c.closeFile()
+	}
+	c.mu.Unlock()
+	c.uploads.Wait()
+}
+
+// CloseAndErr closes the collector and returns the first write, flush, close,
+// or upload error observed by the collector.
+func (c *CSVCollector[R]) CloseAndErr() error {
+	c.Close()
+	return c.Err()
+}
+
+// Err returns the first write, flush, close, or upload error observed by the
+// collector.
+func (c *CSVCollector[R]) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	return c.err
+}
+
+func (c *CSVCollector[R]) setErr(err error) {
+	if err == nil {
+		return
+	}
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.err == nil {
+		c.err = err
 	}
 }
 
@@ -100,9 +389,15 @@ func (c *CSVCollector[R]) RunFlush(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case <-t.C:
+		case now := <-t.C:
 			c.mu.Lock()
-			c.writer.Flush()
+			if c.file != nil {
+				if c.full(now) {
+					c.rotate(now)
+				} else if c.writer.Flush(); c.writer.Error() != nil {
+					c.setErr(fmt.Errorf("flush CSV file: %w", c.writer.Error()))
+				}
+			}
 			c.mu.Unlock()
 		}
 	}
@@ -115,6 +410,8 @@ type gobCollectorConfig struct {
 	bufferSize       int
 	gzipEnabled      bool
 	compressionLevel int
+	uploader         Uploader
+	removeLocal      bool
 }
 
 // WithGobCollectorBufferSize configures how many results can queue before Collect blocks.
@@ -135,12 +432,23 @@ func WithGobCollectorGzip(level int) GobCollectorOption {
 	}
 }
 
+// WithGobCollectorUpload uploads the finished file on Close and removes the
+// local copy once stored if removeLocal is set. Upload errors are reported by Err.
+func WithGobCollectorUpload(uploader Uploader, removeLocal bool) GobCollectorOption {
+	return func(cfg *gobCollectorConfig) {
+		cfg.uploader, cfg.removeLocal = uploader, removeLocal
+	}
+}
+
 var errGobCollectorClosed = errors.New("gob collector is closed")
 
 // GobCollector stores results as an async gob stream. It is a good default for
 // very large experiments where CSV conversion and writer lock contention are too expensive.
 type GobCollector[R any] struct {
 	file          *os.File
+	path          string
+	uploader      Uploader
+	removeLocal   bool
 	buf           *bufio.Writer
 	gzipWriter    *gzip.Writer
 	encoder       *gob.Encoder
@@ -188,6 +496,9 @@ func NewGobCollector[R any](filePath string, flushInterval time.Duration, opts .
 
 	c := &GobCollector[R]{
 		file:          file,
+		path:          filePath,
+		uploader:      cfg.uploader,
+		removeLocal:   cfg.removeLocal,
 		buf:           buf,
 		gzipWriter:    gzipWriter,
 		encoder:       gob.NewEncoder(writer),
@@ -216,7 +527,25 @@ func (c *GobCollector[R]) Collect(result R) {
 	c.results <- result
 }
 
-// Close drains queued results, flushes the gob stream, and closes the file.
+// CollectBatch queues results with one check of the collector's state.
+func (c *GobCollector[R]) CollectBatch(results []R) {
+	c.collectMu.Lock()
+	if c.closed {
+		c.collectMu.Unlock()
+		c.setErr(errGobCollectorClosed)
+		return
+	}
+	c.collectWG.Add(1)
+	c.collectMu.Unlock()
+
+	defer c.collectWG.Done()
+	for _, result := range results {
+		c.results <- result
+	}
+}
+
+// Close drains queued results, flushes the gob stream, closes the file, and
+// uploads it if an uploader is configured.
 func (c *GobCollector[R]) Close() {
 	c.closeOnce.Do(func() {
 		c.collectMu.Lock()
@@ -225,6 +554,9 @@ func (c *GobCollector[R]) Close() {
 		c.collectWG.Wait()
 		close(c.results)
 		<-c.done
+		if c.uploader != nil {
+			c.setErr(upload(c.uploader, c.path, c.removeLocal))
+		}
 	})
 }
 
@@ -297,6 +629,5 @@ func (c *GobCollector[R]) setErr(err error) {
 	defer c.errMu.Unlock()
 	if c.err == nil {
 		c.err = err
-		fmt.Printf("Error writing gob record: %v\n", err)
 	}
 }
@@ -2,9 +2,12 @@ package go_loadgen
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/gob"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -261,6 +264,23 @@ func TestCSVCollector_MultipleClose(t *testing.T) {
 	// Should not crash or cause issues
 }
 
+func TestCSVCollector_CollectAfterCloseReportsError(t *testing.T) {
+	filename := "test_csv_collect_after_close.csv"
+	defer os.Remove(filename)
+
+	collector, err := NewCSVCollector[testCSVData](filename, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+
+	collector.Close()
+	collector.Collect(testCSVData{ID: 1, Message: "late", Value: 1.0})
+
+	if err := collector.Err(); !errors.Is(err, errCSVCollectorClosed) {
+		t.Fatalf("Expected %v after collecting on a closed collector, got %v", errCSVCollectorClosed, err)
+	}
+}
+
 func TestGobCollector_Collect(t *testing.T) {
 	filename := "test_collect.gob"
 	defer os.Remove(filename)
@@ -466,3 +486,189 @@ func readGobRecords[R any](t *testing.T, filename string, compressed bool) []R {
 		records = append(records, record)
 	}
 }
+
+func TestCSVCollector_TagColumns(t *testing.T) {
+	filename := "test_tags.csv"
+	defer os.Remove(filename)
+
+	collector, err := NewCSVCollector[testCSVData](filename, time.Second, WithCSVCollectorTags("variant", "region"))
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	client := ClientFunc[testRequest, testCSVData](func(context.Context, testRequest) testCSVData {
+		return testCSVData{ID: 1, Message: "ok", Value: 1}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, collector)},
+		Phases: []Phase{{
+			Tags:     map[string]string{"variant": "b"},
+			Duration: 10 * time.Millisecond,
+			RPS:      100,
+			Targets:  []Target{{Endpoint: "one", Weight: 1}},
+		}},
+	})
+	mustRun(t, workload)
+	collector.Collect(testCSVData{ID: 2, Message: "untagged", Value: 2})
+	collector.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || lines[0] != "id,message,value,variant,region" || lines[1] != "1,ok,1.00,b," || lines[2] != "2,untagged,2.00,," {
+		t.Fatalf("lines=%q, want tag columns after the result's own", lines)
+	}
+}
+
+func TestCSVCollector_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	collector, err := NewCSVCollector[testCSVData](filepath.Join(dir, "results.csv"), time.Hour, WithCSVCollectorRotation(40, 0))
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	for i := range 6 {
+		collector.Collect(testCSVData{ID: i, Message: "rotate", Value: 1})
+	}
+	collector.Close()
+
+	files := collector.Files()
+	if len(files) != 3 {
+		t.Fatalf("files=%q, want a new file every two records", files)
+	}
+	var records int
+	for _, file := range files {
+		if !strings.HasPrefix(filepath.Base(file), "results-") || filepath.Ext(file) != ".csv" {
+			t.Fatalf("file=%q, want a timestamped name", file)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if lines[0] != "id,message,value" {
+			t.Fatalf("file %s starts with %q, want the header", file, lines[0])
+		}
+		records += len(lines) - 1
+	}
+	if records != 6 {
+		t.Fatalf("records=%d across files, want 6", records)
+	}
+
+	timed, err := NewCSVCollector[testCSVData](filepath.Join(dir, "timed.csv"), 10*time.Millisecond, WithCSVCollectorRotation(0, 20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	timed.Collect(testCSVData{ID: 1})
+	time.Sleep(50 * time.Millisecond)
+	timed.Close()
+	if files := timed.Files(); len(files) != 2 {
+		t.Fatalf("files=%q, want one rotation after the interval and no empty files", files)
+	}
+}
+
+func TestCSVCollector_ReportsWriteErrors(t *testing.T) {
+	collector, err := NewCSVCollector[testCSVData](filepath.Join(t.TempDir(), "results.csv"), time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	// Simulate the disk going away underneath the collector.
+	collector.file.Close()
+
+	client := ClientFunc[testRequest, testCSVData](func(context.Context, testRequest) testCSVData {
+		return testCSVData{ID: 1, Message: "lost"}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  50 * time.Millisecond,
+		Endpoints: map[string]Endpoint{"api": mustEndpoint(t, client, testProvider{}, collector)},
+		Phases:    []Phase{{Duration: 50 * time.Millisecond, RPS: 200, Targets: []Target{{Endpoint: "api", Weight: 1}}}},
+	})
+	report, err := workload.Run(context.Background())
+	if err == nil || len(report.CollectorErrors) != 1 || !strings.Contains(err.Error(), `endpoint "api" collector`) {
+		t.Fatalf("err=%v collector errors=%v, want the failed flush reported", err, report.CollectorErrors)
+	}
+	if err := collector.CloseAndErr(); err == nil {
+		t.Fatal("Expected CloseAndErr to report the failed flush")
+	}
+}
+
+func TestCSVCollector_MetadataColumns(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "metadata.csv")
+	collector, err := NewCSVCollector[testCSVData](filename, time.Second, WithCSVCollectorMetadata())
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	client := ClientFunc[testRequest, testCSVData](func(context.Context, testRequest) testCSVData {
+		return testCSVData{ID: 1, Message: "ok", Value: 1}
+	})
+	workload := mustWorkload(t, Spec{
+		Name:      "checkout",
+		Duration:  time.Second,
+		Workers:   2,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, collector)},
+		Phases:    []Phase{{Name: "steady", Duration: 10 * time.Millisecond, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	mustRun(t, workload)
+	collector.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) < 2 || lines[0] != "timestamp,workload,phase,worker,id,message,value" {
+		t.Fatalf("lines=%q, want metadata columns before the result's own", lines)
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		if _, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil || fields[1] != "checkout" || fields[2] != "steady" || (fields[3] != "0" && fields[3] != "1") {
+			t.Fatalf("line=%q, want timestamp, workload, phase, and worker", line)
+		}
+	}
+}
+
+func TestCSVCollector_AppendSkipsExistingHeader(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "append.csv")
+	for stage := range 2 {
+		collector, err := NewCSVCollector[testCSVData](filename, time.Second, WithCSVCollectorAppend())
+		if err != nil {
+			t.Fatalf("Failed to create CSV collector: %v", err)
+		}
+		collector.Collect(testCSVData{ID: stage, Message: "stage", Value: 1})
+		if err := collector.CloseAndErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || lines[0] != "id,message,value" || lines[1] != "0,stage,1.00" || lines[2] != "1,stage,1.00" {
+		t.Fatalf("lines=%q, want both stages under one header", lines)
+	}
+}
+
+func TestCSVCollector_BatchesUnlessContextColumnsAreWritten(t *testing.T) {
+	dir := t.TempDir()
+	client := ClientFunc[testRequest, testCSVData](func(context.Context, testRequest) testCSVData { return testCSVData{} })
+	plain, err := NewCSVCollector[testCSVData](filepath.Join(dir, "plain.csv"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	defer plain.Close()
+	tagged, err := NewCSVCollector[testCSVData](filepath.Join(dir, "tagged.csv"), time.Second, WithCSVCollectorTags("variant"))
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	defer tagged.Close()
+
+	if !mustEndpoint(t, client, testProvider{}, plain).batching() {
+		t.Fatal("expected a plain CSV collector to be batched")
+	}
+	if mustEndpoint(t, client, testProvider{}, tagged).batching() {
+		t.Fatal("expected a CSV collector with tag columns to receive each request context")
+	}
+}
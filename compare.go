@@ -0,0 +1,129 @@
+package go_loadgen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tolerance bounds the change between two runs that Compare accepts. Zero
+// fields accept no regression at all.
+type Tolerance struct {
+	// Throughput is the accepted fractional drop in throughput, such as 0.05
+	// for 5%.
+	Throughput float64
+	// Latency is the accepted fractional increase of the mean and each
+	// percentile.
+	Latency float64
+	// ErrorRate is the accepted absolute increase of the error rate, such as
+	// 0.001 for a tenth of a percentage point.
+	ErrorRate float64
+}
+
+// Comparison is the difference between a baseline and a candidate run, such
+// as the releases before and after a change.
+type Comparison struct {
+	// Phases holds every phase present in both summaries, then the total.
+	Phases []PhaseComparison
+	// Missing names phases present in only one of the summaries.
+	Missing   []string
+	Regressed bool
+}
+
+// PhaseComparison compares one phase of two runs.
+type PhaseComparison struct {
+	Phase     string
+	Metrics   []MetricComparison
+	Regressed bool
+}
+
+// MetricComparison compares one metric of two runs. Latencies are in
+// milliseconds.
+type MetricComparison struct {
+	Name      string
+	Baseline  float64
+	Candidate float64
+	// Change is the candidate's change relative to the baseline, or zero when
+	// the baseline is zero.
+	Change    float64
+	Regressed bool
+}
+
+// Compare reports how candidate differs from baseline, phase by phase, and
+// marks changes beyond tolerance as regressions: lower throughput, higher
+// latency, or a higher error rate.
+func Compare(baseline, candidate Summary, tolerance Tolerance) Comparison {
+	var c Comparison
+	candidates := make(map[string]PhaseSummary, len(candidate.Phases))
+	for _, phase := range candidate.Phases {
+		candidates[phase.Phase] = phase
+	}
+	matched := make(map[string]bool, len(baseline.Phases))
+	for _, phase := range baseline.Phases {
+		other, ok := candidates[phase.Phase]
+		if !ok {
+			c.Missing = append(c.Missing, phase.Phase)
+			continue
+		}
+		matched[phase.Phase] = true
+		c.Phases = append(c.Phases, comparePhase(phase, other, tolerance))
+	}
+	for _, phase := range candidate.Phases {
+		if !matched[phase.Phase] {
+			c.Missing = append(c.Missing, phase.Phase)
+		}
+	}
+	c.Phases = append(c.Phases, comparePhase(baseline.Total, candidate.Total, tolerance))
+	for _, phase := range c.Phases {
+		c.Regressed = c.Regressed || phase.Regressed
+	}
+	return c
+}
+
+func comparePhase(baseline, candidate PhaseSummary, tolerance Tolerance) PhaseComparison {
+	p := PhaseComparison{Phase: baseline.Phase}
+	add := func(name string, base, next float64, regressed bool) {
+		metric := MetricComparison{Name: name, Baseline: base, Candidate: next, Regressed: regressed}
+		if base != 0 {
+			metric.Change = (next - base) / base
+		}
+		p.Metrics = append(p.Metrics, metric)
+		p.Regressed = p.Regressed || regressed
+	}
+	add("throughput", baseline.Throughput, candidate.Throughput, candidate.Throughput < baseline.Throughput*(1-tolerance.Throughput))
+	add("error rate", baseline.ErrorRate, candidate.ErrorRate, candidate.ErrorRate > baseline.ErrorRate+tolerance.ErrorRate)
+	latencies := []struct {
+		name            string
+		base, candidate time.Duration
+	}{
+		{"mean", baseline.Mean, candidate.Mean},
+		{"p50", baseline.P50, candidate.P50},
+		{"p90", baseline.P90, candidate.P90},
+		{"p95", baseline.P95, candidate.P95},
+		{"p99", baseline.P99, candidate.P99},
+	}
+	for _, l := range latencies {
+		base, next := float64(l.base)/float64(time.Millisecond), float64(l.candidate)/float64(time.Millisecond)
+		add(l.name, base, next, next > base*(1+tolerance.Latency))
+	}
+	return p
+}
+
+// String formats the comparison with one line per metric, marking
+// regressions.
+func (c Comparison) String() string {
+	var b strings.Builder
+	for _, phase := range c.Phases {
+		for _, metric := range phase.Metrics {
+			mark := ""
+			if metric.Regressed {
+				mark = "  REGRESSION"
+			}
+			fmt.Fprintf(&b, "%-16s %-10s %12.4g -> %-12.4g %+7.1f%%%s\n", phase.Phase, metric.Name, metric.Baseline, metric.Candidate, 100*metric.Change, mark)
+		}
+	}
+	for _, phase := range c.Missing {
+		fmt.Fprintf(&b, "%-16s only in one run\n", phase)
+	}
+	return b.String()
+}
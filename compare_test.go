@@ -0,0 +1,44 @@
+package go_loadgen
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareFlagsRegressionsBeyondTolerance(t *testing.T) {
+	baseline := Summary{
+		Phases: []PhaseSummary{
+			{Phase: "steady", Throughput: 1000, ErrorRate: 0.001, Mean: 10 * time.Millisecond, P50: 8 * time.Millisecond, P90: 15 * time.Millisecond, P95: 20 * time.Millisecond, P99: 40 * time.Millisecond},
+			{Phase: "retired", Throughput: 10},
+		},
+		Total: PhaseSummary{Phase: "total", Throughput: 1000},
+	}
+	candidate := Summary{
+		Phases: []PhaseSummary{
+			{Phase: "steady", Throughput: 960, ErrorRate: 0.0015, Mean: 10 * time.Millisecond, P50: 8 * time.Millisecond, P90: 15 * time.Millisecond, P95: 21 * time.Millisecond, P99: 60 * time.Millisecond},
+		},
+		Total: PhaseSummary{Phase: "total", Throughput: 990},
+	}
+
+	comparison := Compare(baseline, candidate, Tolerance{Throughput: 0.05, Latency: 0.1, ErrorRate: 0.001})
+	if !comparison.Regressed || len(comparison.Phases) != 2 || len(comparison.Missing) != 1 || comparison.Missing[0] != "retired" {
+		t.Fatalf("comparison=%+v", comparison)
+	}
+	var regressed []string
+	for _, metric := range comparison.Phases[0].Metrics {
+		if metric.Regressed {
+			regressed = append(regressed, metric.Name)
+		}
+	}
+	if len(regressed) != 1 || regressed[0] != "p99" || comparison.Phases[1].Regressed {
+		t.Fatalf("regressed=%v total=%+v, want only the p99 beyond tolerance", regressed, comparison.Phases[1])
+	}
+	if text := comparison.String(); !strings.Contains(text, "p99") || !strings.Contains(text, "+50.0%  REGRESSION") {
+		t.Fatalf("comparison text:\n%s", text)
+	}
+
+	if Compare(baseline, baseline, Tolerance{}).Regressed {
+		t.Fatal("identical runs must not regress")
+	}
+}
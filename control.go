@@ -0,0 +1,75 @@
+package go_loadgen
+
+import (
+	"context"
+	"time"
+)
+
+// RateController adapts a phase's offered rate while it runs. NextRate is called
+// every Phase.ControlEvery with the current rate and returns the next one; a
+// zero result keeps the current rate.
+type RateController interface {
+	NextRate(current uint64) uint64
+}
+
+// Observation is a window of endpoint results used by LatencyController.
+type Observation struct {
+	Latency   time.Duration
+	ErrorRate float64
+}
+
+// LatencyController searches for the highest rate that keeps observed latency
+// and error rate within limits. It adds Step while both hold and multiplies the
+// rate by Backoff when either is exceeded, clamped to [Min, Max].
+type LatencyController struct {
+	// Observe returns the results since the previous call, typically a
+	// percentile and error rate read from an aggregating collector.
+	Observe func() Observation
+	// MaxLatency and MaxErrorRate are the limits to hold. Zero disables a limit.
+	MaxLatency   time.Duration
+	MaxErrorRate float64
+	Step         uint64
+	// Backoff is the decrease factor. Zero uses one half.
+	Backoff float64
+	// Min and Max bound the rate. Zero Min is one; zero Max is unbounded.
+	Min uint64
+	Max uint64
+}
+
+// NextRate implements RateController.
+func (c *LatencyController) NextRate(current uint64) uint64 {
+	observation := c.Observe()
+	next := current + c.Step
+	if (c.MaxLatency > 0 && observation.Latency > c.MaxLatency) || (c.MaxErrorRate > 0 && observation.ErrorRate > c.MaxErrorRate) {
+		backoff := c.Backoff
+		if backoff <= 0 || backoff >= 1 {
+			backoff = 0.5
+		}
+		next = uint64(float64(current) * backoff)
+	}
+	if c.Max != 0 {
+		next = min(next, c.Max)
+	}
+	return max(next, c.Min, 1)
+}
+
+// controlRate feeds the phase's controller until ctx ends. The adapted rate
+// applies to the phase's current run and is reset when the phase starts again.
+func (r *run) controlRate(ctx context.Context, phase *compiledPhase) {
+	ticker := r.workload.clock.NewTicker(phase.phase.ControlEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			current := phase.rateAt(r.elapsed() - phase.phase.StartAt)
+			if next := phase.phase.Controller.NextRate(current); next != 0 {
+				if phase.ceiling != 0 {
+					next = min(next, phase.ceiling)
+				}
+				phase.adapted.Store(next)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package go_loadgen
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLatencyControllerIncreasesUntilLimit(t *testing.T) {
+	observation := Observation{Latency: 10 * time.Millisecond}
+	controller := &LatencyController{
+		Observe:    func() Observation { return observation },
+		MaxLatency: 50 * time.Millisecond,
+		Step:       100,
+		Min:        50,
+		Max:        1_000,
+	}
+	if got := controller.NextRate(500); got != 600 {
+		t.Fatalf("rate below the latency limit=%d, want 600", got)
+	}
+	if got := controller.NextRate(950); got != 1_000 {
+		t.Fatalf("rate near the maximum=%d, want 1000", got)
+	}
+	observation.Latency = 80 * time.Millisecond
+	if got := controller.NextRate(600); got != 300 {
+		t.Fatalf("rate above the latency limit=%d, want 300", got)
+	}
+	if got := controller.NextRate(60); got != 50 {
+		t.Fatalf("rate near the minimum=%d, want 50", got)
+	}
+}
+
+func TestPhaseControllerAdaptsRate(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	var observed atomic.Int64
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases: []Phase{{
+			Duration:     200 * time.Millisecond,
+			RPS:          10,
+			Controller:   &LatencyController{Observe: func() Observation { observed.Add(1); return Observation{} }, Step: 1_000, Max: 1_000},
+			ControlEvery: 20 * time.Millisecond,
+			Targets:      []Target{{Endpoint: "one", Weight: 1}},
+		}},
+	})
+
+	report := mustRun(t, workload)
+	if report.Scheduled < 50 {
+		t.Fatalf("scheduled=%d, want the controller to raise the rate above 10 RPS", report.Scheduled)
+	}
+	calls := observed.Load()
+	time.Sleep(50 * time.Millisecond)
+	if observed.Load() != calls {
+		t.Fatal("controller observed results after Run returned")
+	}
+	if _, err := NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: []Phase{{Duration: time.Second, RPS: 1, Controller: &LatencyController{}, Targets: []Target{{Endpoint: "one", Weight: 1}}}}}); err == nil {
+		t.Fatal("expected controller without an interval to be rejected")
+	}
+	if _, err := NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: []Phase{{Duration: time.Second, RPS: 1, Controller: &LatencyController{}, ControlEvery: time.Second, Targets: []Target{{Endpoint: "one", Weight: 1}}}}}); err == nil {
+		t.Fatal("expected a latency controller without Observe to be rejected")
+	}
+}
@@ -0,0 +1,231 @@
+package go_loadgen
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StructCSVCollector writes any struct result to a CSV file using its csv
+// struct tags, so result types need not implement CSVSerializable. Each
+// exported field is a column named by its tag, or by the field name when
+// untagged; fields tagged csv:"-" are skipped and embedded structs are
+// flattened. A ",ms" tag option writes a time.Duration as fractional
+// milliseconds, as in csv:"latency_ms,ms".
+//
+// Fields are read by reflection on every result. Implement CSVSerializable and
+// use CSVCollector where that cost matters, or NewCSVFuncCollector to convert
+// results with a function instead of tags.
+type StructCSVCollector[R any] struct {
+	headers   []string
+	encode    func(R) ([]string, error)
+	collector *CSVCollector[csvRow]
+}
+
+// NewStructCSVCollector creates a collector for struct results, or pointers to
+// them, with the same options and flushing as NewCSVCollector.
+func NewStructCSVCollector[R any](filePath string, flushInterval time.Duration, opts ...CSVCollectorOption) (*StructCSVCollector[R], error) {
+	codec, err := newCSVCodec(reflect.TypeFor[R]())
+	if err != nil {
+		return nil, err
+	}
+	encode := func(result R) ([]string, error) {
+		return codec.record(reflect.ValueOf(&result).Elem()), nil
+	}
+	return NewCSVFuncCollector(filePath, flushInterval, codec.headers, encode, opts...)
+}
+
+// NewCSVFuncCollector creates a collector that converts each result to a
+// record with encode, for result types that cannot implement CSVSerializable
+// or need fields redacted or reshaped. Records must match headers in length.
+// Results that fail to encode are skipped and reported by Err.
+func NewCSVFuncCollector[R any](filePath string, flushInterval time.Duration, headers []string, encode func(R) ([]string, error), opts ...CSVCollectorOption) (*StructCSVCollector[R], error) {
+	if encode == nil {
+		return nil, fmt.Errorf("csv func collector needs an encode function")
+	}
+	collector, err := NewCSVCollector[csvRow](filePath, flushInterval, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &StructCSVCollector[R]{headers: headers, encode: encode, collector: collector}, nil
+}
+
+// Collect writes a result to the CSV file.
+func (c *StructCSVCollector[R]) Collect(result R) {
+	if row, ok := c.row(result); ok {
+		c.collector.Collect(row)
+	}
+}
+
+// CollectContext writes a result with the metadata and tags of the phase that
+// issued it.
+func (c *StructCSVCollector[R]) CollectContext(ctx context.Context, result R) {
+	if row, ok := c.row(result); ok {
+		c.collector.CollectContext(ctx, row)
+	}
+}
+
+// CollectBatch writes results under one lock.
+func (c *StructCSVCollector[R]) CollectBatch(results []R) {
+	rows := make([]csvRow, 0, len(results))
+	for _, result := range results {
+		if row, ok := c.row(result); ok {
+			rows = append(rows, row)
+		}
+	}
+	c.collector.CollectBatch(rows)
+}
+
+func (c *StructCSVCollector[R]) row(result R) (csvRow, bool) {
+	record, err := c.encode(result)
+	if err == nil && len(record) != len(c.headers) {
+		err = fmt.Errorf("record has %d fields, want %d", len(record), len(c.headers))
+	}
+	if err != nil {
+		c.collector.setErr(fmt.Errorf("encode result: %w", err))
+		return csvRow{}, false
+	}
+	return csvRow{headers: c.headers, record: record}, true
+}
+
+func (c *StructCSVCollector[R]) needsContext() bool { return c.collector.needsContext() }
+
+// Files returns the paths written so far, oldest first.
+func (c *StructCSVCollector[R]) Files() []string { return c.collector.Files() }
+
+// Close flushes the collector, closes the file, and waits for uploads.
+func (c *StructCSVCollector[R]) Close() { c.collector.Close() }
+
+// CloseAndErr closes the collector and returns its first error.
+func (c *StructCSVCollector[R]) CloseAndErr() error { return c.collector.CloseAndErr() }
+
+// Err returns the first write, flush, close, or upload error observed by the
+// collector.
+func (c *StructCSVCollector[R]) Err() error { return c.collector.Err() }
+
+// csvRow is a result already converted to CSV columns.
+type csvRow struct {
+	headers []string
+	record  []string
+}
+
+func (r csvRow) CSVHeaders() []string { return r.headers }
+
+func (r csvRow) CSVRecord() []string { return r.record }
+
+// csvCodec converts one struct type to CSV columns.
+type csvCodec struct {
+	pointer bool
+	headers []string
+	fields  []csvField
+}
+
+type csvField struct {
+	index        []int
+	milliseconds bool
+}
+
+var (
+	durationType      = reflect.TypeFor[time.Duration]()
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func newCSVCodec(t reflect.Type) (*csvCodec, error) {
+	codec := &csvCodec{}
+	if t.Kind() == reflect.Pointer {
+		codec.pointer, t = true, t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv struct collector needs a struct result, got %s", t)
+	}
+	for _, field := range reflect.VisibleFields(t) {
+		// Embedded structs contribute their promoted fields, not a column.
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if !field.IsExported() || (field.Anonymous && embedded.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := field.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, option, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		if option == "ms" && field.Type != durationType {
+			return nil, fmt.Errorf("csv field %s: the ms option needs a time.Duration", field.Name)
+		}
+		codec.headers = append(codec.headers, name)
+		codec.fields = append(codec.fields, csvField{index: field.Index, milliseconds: option == "ms"})
+	}
+	if len(codec.fields) == 0 {
+		return nil, fmt.Errorf("csv struct collector: %s has no exported fields", t)
+	}
+	return codec, nil
+}
+
+func (c *csvCodec) record(value reflect.Value) []string {
+	record := make([]string, len(c.fields))
+	if c.pointer {
+		if value.IsNil() {
+			return record
+		}
+		value = value.Elem()
+	}
+	for i, field := range c.fields {
+		// Fields promoted through a nil embedded pointer are left empty.
+		if fieldValue, err := value.FieldByIndexErr(field.index); err == nil {
+			record[i] = formatCSVField(fieldValue, field.milliseconds)
+		}
+	}
+	return record
+}
+
+func formatCSVField(value reflect.Value, milliseconds bool) string {
+	switch {
+	case value.Type() == durationType:
+		if milliseconds {
+			return strconv.FormatFloat(float64(value.Int())/float64(time.Millisecond), 'f', -1, 64)
+		}
+		return time.Duration(value.Int()).String()
+	case value.Type() == timeType:
+		return value.Interface().(time.Time).Format(time.RFC3339Nano)
+	case value.Type().Implements(textMarshalerType):
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return ""
+		}
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return ""
+		}
+		return formatCSVField(value.Elem(), milliseconds)
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,116 @@
+package go_loadgen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type structTestBase struct {
+	Endpoint string `csv:"endpoint"`
+}
+
+type structTestResult struct {
+	structTestBase
+	Status  int           `csv:"status"`
+	Latency time.Duration `csv:"latency_ms,ms"`
+	Wait    time.Duration
+	Error   *string `csv:"error"`
+	Body    []byte  `csv:"-"`
+	secret  string
+}
+
+func TestStructCSVCollectorUsesTags(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "struct.csv")
+	collector, err := NewStructCSVCollector[*structTestResult](filename, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	failure := "reset"
+	collector.Collect(&structTestResult{structTestBase: structTestBase{Endpoint: "api"}, Status: 200, Latency: 1500 * time.Microsecond, Wait: time.Second, Body: []byte("x"), secret: "x"})
+	collector.Collect(&structTestResult{Status: 502, Error: &failure})
+	collector.Collect(nil)
+	if err := collector.CloseAndErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	want := "endpoint,status,latency_ms,Wait,error\napi,200,1.5,1s,\n,502,0,0s,reset\n,,,,\n"
+	if string(content) != want {
+		t.Fatalf("content=%q, want %q", content, want)
+	}
+}
+
+type structTestEmbedded struct {
+	*structTestBase
+	Status int `csv:"status"`
+}
+
+func TestStructCSVCollectorFlattensEmbeddedPointers(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "embedded.csv")
+	collector, err := NewStructCSVCollector[structTestEmbedded](filename, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	collector.Collect(structTestEmbedded{structTestBase: &structTestBase{Endpoint: "api"}, Status: 200})
+	collector.Collect(structTestEmbedded{Status: 503})
+	if err := collector.CloseAndErr(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	if want := "endpoint,status\napi,200\n,503\n"; string(content) != want {
+		t.Fatalf("content=%q, want %q", content, want)
+	}
+}
+
+func TestStructCSVCollectorRejectsUnsupportedTypes(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewStructCSVCollector[int](filepath.Join(dir, "int.csv"), time.Second); err == nil {
+		t.Fatal("expected a non-struct result to be rejected")
+	}
+	type badOption struct {
+		Latency int `csv:"latency,ms"`
+	}
+	if _, err := NewStructCSVCollector[badOption](filepath.Join(dir, "option.csv"), time.Second); err == nil || !strings.Contains(err.Error(), "ms option") {
+		t.Fatalf("err=%v, want the ms option on a non-duration rejected", err)
+	}
+}
+
+func TestCSVFuncCollectorEncodesAndReportsErrors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "func.csv")
+	encode := func(result structTestResult) ([]string, error) {
+		if result.Status == 0 {
+			return nil, errors.New("no status")
+		}
+		return []string{result.Endpoint, strconv.Itoa(result.Status)}, nil
+	}
+	collector, err := NewCSVFuncCollector(filename, time.Second, []string{"endpoint", "status"}, encode)
+	if err != nil {
+		t.Fatalf("Failed to create CSV collector: %v", err)
+	}
+	collector.Collect(structTestResult{structTestBase: structTestBase{Endpoint: "api"}, Status: 200})
+	collector.Collect(structTestResult{structTestBase: structTestBase{Endpoint: "api"}})
+	collector.CollectBatch([]structTestResult{{Status: 503}})
+	err = collector.CloseAndErr()
+	if err == nil || !strings.Contains(err.Error(), "no status") {
+		t.Fatalf("err=%v, want the encode error", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	if want := "endpoint,status\napi,200\n,503\n"; string(content) != want {
+		t.Fatalf("content=%q, want %q", content, want)
+	}
+}
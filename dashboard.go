@@ -0,0 +1,242 @@
+package go_loadgen
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dashboardSlots is the number of slots a dashboard's rolling window rotates
+// through; it redraws once per slot.
+const dashboardSlots = 10
+
+// Dashboard is a live terminal view of a running workload: the current
+// phases, target versus achieved throughput, and the error rate and latency
+// percentiles over a rolling window. Pass it in Spec.Observers and call Run
+// alongside the workload; it redraws in place using ANSI escape codes.
+type Dashboard struct {
+	out    io.Writer
+	window time.Duration
+	// started is when the first sample arrived, in Unix nanoseconds.
+	started atomic.Int64
+	slots   [dashboardSlots]dashboardSlot
+	// rotations counts slots begun after the first; the current slot is
+	// rotations modulo dashboardSlots.
+	rotations atomic.Uint64
+	phases    livePhases
+	// mu serializes redraws.
+	mu sync.Mutex
+	// filled counts completed slots, up to the whole window.
+	filled int
+	// lines is the height of the previous frame, which the next one
+	// overwrites.
+	lines int
+}
+
+type dashboardSlot struct {
+	requests  atomic.Uint64
+	failed    atomic.Uint64
+	latencies histogram
+}
+
+// livePhases tracks the phases observed by a live view, keyed by workload and
+// index, so workloads sharing a view, such as in a Group, stay apart. Lookups
+// of known phases take only a read lock.
+type livePhases struct {
+	mu     sync.RWMutex
+	phases map[phaseKeyOf]*livePhase
+}
+
+// livePhase is a phase's identity with its latest rate and the interval it
+// was last seen in, plus one.
+type livePhase struct {
+	info PhaseInfo
+	rps  atomic.Uint64
+	seen atomic.Uint64
+}
+
+// observe records that info was seen in interval.
+func (p *livePhases) observe(info PhaseInfo, interval uint64) {
+	key := phaseKeyOf{workload: info.Workload, index: info.Index}
+	p.mu.RLock()
+	phase, ok := p.phases[key]
+	p.mu.RUnlock()
+	if !ok {
+		p.mu.Lock()
+		if p.phases == nil {
+			p.phases = make(map[phaseKeyOf]*livePhase)
+		}
+		if phase, ok = p.phases[key]; !ok {
+			phase = &livePhase{info: info}
+			p.phases[key] = phase
+		}
+		p.mu.Unlock()
+	}
+	phase.rps.Store(info.RPS)
+	if phase.seen.Load() != interval+1 {
+		phase.seen.Store(interval + 1)
+	}
+}
+
+// since returns the phases seen in interval or later, ordered by workload and
+// index, with their latest rates.
+func (p *livePhases) since(interval uint64) []PhaseInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var infos []PhaseInfo
+	for _, phase := range p.phases {
+		if phase.seen.Load() > interval {
+			info := phase.info
+			info.RPS = phase.rps.Load()
+			infos = append(infos, info)
+		}
+	}
+	slices.SortFunc(infos, func(a, b PhaseInfo) int {
+		return cmp.Or(cmp.Compare(a.Workload, b.Workload), cmp.Compare(a.Index, b.Index))
+	})
+	return infos
+}
+
+// phaseLabel names a phase in a live view, prefixed by its workload's name if
+// any.
+func phaseLabel(info PhaseInfo) string {
+	name := info.Name
+	if name == "" {
+		name = fmt.Sprintf("phase %d", info.Index)
+	}
+	if info.Workload != "" {
+		name = info.Workload + "/" + name
+	}
+	return name
+}
+
+// NewDashboard creates a dashboard drawing to out, usually os.Stdout, with
+// rates and percentiles over roughly the last window, ten seconds when window
+// is not positive. Shorter windows than ten nanoseconds, which cannot be split
+// into the window's slots, are raised to that.
+func NewDashboard(out io.Writer, window time.Duration) *Dashboard {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	window = max(window, dashboardSlots)
+	d := &Dashboard{out: out, window: window}
+	for i := range d.slots {
+		d.slots[i].latencies = newHistogram(defaultHistogramSubBits)
+	}
+	return d
+}
+
+// Observe implements Observer.
+func (d *Dashboard) Observe(sample Sample) {
+	if d.started.Load() == 0 {
+		d.started.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	rotation := d.rotations.Load()
+	slot := &d.slots[rotation%dashboardSlots]
+	slot.requests.Add(1)
+	if sample.Failed {
+		slot.failed.Add(1)
+	}
+	slot.latencies.record(sample.Latency)
+	d.phases.observe(sample.Phase, rotation)
+}
+
+// Run redraws the dashboard every tenth of its window until ctx is done, then
+// draws a final frame.
+func (d *Dashboard) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.window / dashboardSlots)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.mu.Lock()
+			d.draw()
+			d.mu.Unlock()
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.rotate()
+			d.draw()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// rotate completes the current slot and clears the oldest for reuse. d.mu
+// must be held.
+func (d *Dashboard) rotate() {
+	// The oldest slot is cleared before samples move to it.
+	slot := &d.slots[(d.rotations.Load()+1)%dashboardSlots]
+	slot.requests.Store(0)
+	slot.failed.Store(0)
+	slot.latencies.reset()
+	d.rotations.Add(1)
+	d.filled = min(d.filled+1, dashboardSlots-1)
+}
+
+// draw renders a frame over the previous one. d.mu must be held.
+func (d *Dashboard) draw() {
+	var requests, failed uint64
+	var window latencies
+	for i := range d.slots {
+		requests += d.slots[i].requests.Load()
+		failed += d.slots[i].failed.Load()
+		window.add(&d.slots[i].latencies)
+	}
+	// Rates are measured over completed slots; the current one is partial.
+	rotation := d.rotations.Load()
+	var completed uint64
+	for i := uint64(1); i <= uint64(d.filled); i++ {
+		completed += d.slots[(rotation-i)%dashboardSlots].requests.Load()
+	}
+	latest := d.phases.since(rotation - uint64(min(d.filled, 1)))
+
+	var b strings.Builder
+	if d.lines > 0 {
+		// Move to the start of the previous frame and clear it.
+		fmt.Fprintf(&b, "\x1b[%dA\x1b[J", d.lines)
+	}
+	elapsed := time.Duration(0)
+	if started := d.started.Load(); started != 0 {
+		elapsed = time.Since(time.Unix(0, started)).Round(time.Second)
+	}
+	fmt.Fprintf(&b, "elapsed     %s\n", elapsed)
+	var target uint64
+	var names []string
+	for _, info := range latest {
+		name := phaseLabel(info)
+		if info.RPS != 0 {
+			name += fmt.Sprintf(" (%d rps)", info.RPS)
+		}
+		names = append(names, name)
+		target += info.RPS
+	}
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	fmt.Fprintf(&b, "phase       %s\n", strings.Join(names, ", "))
+	var achieved float64
+	if d.filled > 0 {
+		achieved = float64(completed) / (d.window / dashboardSlots * time.Duration(d.filled)).Seconds()
+	}
+	fmt.Fprintf(&b, "throughput  %.1f rps achieved", achieved)
+	if target != 0 {
+		fmt.Fprintf(&b, " / %d target", target)
+	}
+	b.WriteString("\n")
+	var rate float64
+	if requests != 0 {
+		rate = 100 * float64(failed) / float64(requests)
+	}
+	fmt.Fprintf(&b, "errors      %.2f%% (%d of %d)\n", rate, failed, requests)
+	fmt.Fprintf(&b, "latency     p50 %s  p95 %s  p99 %s  (last %s)\n",
+		window.percentile(50).Round(time.Microsecond), window.percentile(95).Round(time.Microsecond), window.percentile(99).Round(time.Microsecond), d.window)
+	d.lines = 5
+	io.WriteString(d.out, b.String())
+}
@@ -0,0 +1,61 @@
+package go_loadgen
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardDrawsRollingWindow(t *testing.T) {
+	var out strings.Builder
+	dashboard := NewDashboard(&out, time.Second)
+	steady := PhaseInfo{Index: 1, Name: "steady", RPS: 200}
+	for i := range 20 {
+		dashboard.Observe(Sample{Phase: steady, Latency: time.Duration(i+1) * time.Millisecond, Failed: i == 0})
+	}
+	dashboard.mu.Lock()
+	dashboard.rotate()
+	dashboard.draw()
+	first := out.String()
+	for range dashboardSlots {
+		dashboard.rotate()
+	}
+	dashboard.draw()
+	dashboard.mu.Unlock()
+
+	for _, want := range []string{"phase       steady (200 rps)", "200.0 rps achieved / 200 target", "errors      5.00% (1 of 20)", "p50 10."} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("frame is missing %q:\n%s", want, first)
+		}
+	}
+	second := strings.TrimPrefix(out.String(), first)
+	if !strings.HasPrefix(second, "\x1b[5A\x1b[J") || !strings.Contains(second, "errors      0.00% (0 of 0)") {
+		t.Fatalf("second frame=%q, want it drawn over the first once the window has rolled past", second)
+	}
+}
+
+func TestDashboardRaisesWindowTooShortToSplit(t *testing.T) {
+	dashboard := NewDashboard(io.Discard, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dashboard.Run(ctx)
+	if dashboard.window != dashboardSlots {
+		t.Fatalf("window=%s, want one nanosecond per slot", dashboard.window)
+	}
+}
+
+func TestDashboardKeepsWorkloadsApart(t *testing.T) {
+	var out strings.Builder
+	dashboard := NewDashboard(&out, time.Second)
+	dashboard.Observe(Sample{Phase: PhaseInfo{Workload: "api", Name: "steady", RPS: 100}})
+	dashboard.Observe(Sample{Phase: PhaseInfo{Workload: "batch", Name: "steady", RPS: 20}})
+	dashboard.mu.Lock()
+	dashboard.rotate()
+	dashboard.draw()
+	dashboard.mu.Unlock()
+	if want := "phase       api/steady (100 rps), batch/steady (20 rps)"; !strings.Contains(out.String(), want) {
+		t.Fatalf("frame is missing %q:\n%s", want, out.String())
+	}
+}
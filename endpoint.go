@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Client invokes one endpoint request.
@@ -25,15 +28,121 @@ type Collector[R any] interface {
 	Close()
 }
 
+// ContextCollector is implemented by collectors that record the request
+// context with each result, for example the phase tags from PhaseFromContext.
+// Endpoints call CollectContext instead of Collect.
+type ContextCollector[R any] interface {
+	Collector[R]
+	CollectContext(context.Context, R)
+}
+
+// BatchCollector is implemented by collectors that accept many results at
+// once. Endpoints queue results for them and hand them over in batches of up
+// to 256 every 10 ms and when a run ends, so the collector takes its lock and
+// writes once per batch rather than once per request. Batched results are
+// collected without their request context, so collectors that also
+// implement ContextCollector are not batched while they use it.
+type BatchCollector[R any] interface {
+	Collector[R]
+	CollectBatch([]R)
+}
+
+// BatchAdapter adapts a single-result collector to a BatchCollector. Its
+// CollectBatch calls Collect for each result.
+func BatchAdapter[R any](collector Collector[R]) BatchCollector[R] {
+	if batch, ok := collector.(BatchCollector[R]); ok {
+		return batch
+	}
+	return batchAdapter[R]{collector}
+}
+
+type batchAdapter[R any] struct{ Collector[R] }
+
+func (a batchAdapter[R]) CollectBatch(results []R) {
+	for _, result := range results {
+		a.Collect(result)
+	}
+}
+
+// ErrorReporter is implemented by collectors and observers that write
+// asynchronously and can fail after Collect returns, such as file writers. Run
+// reports their errors in Report.CollectorErrors.
+type ErrorReporter interface {
+	// Err returns the first error observed, or nil.
+	Err() error
+}
+
+// Outcome is implemented by results that can report failure. Failed results
+// count against a phase's ErrorBudget and are reported in Failed.
+type Outcome interface {
+	Failed() bool
+}
+
 // Endpoint is a compiled unit of work. Endpoints are created with NewEndpoint.
 type Endpoint interface {
-	execute(context.Context)
+	execute(context.Context) completion
+	// collectorErr returns the collector's error if it is an ErrorReporter.
+	collectorErr() error
+	// flush hands queued results to a BatchCollector. batching reports
+	// whether there is one.
+	flush()
+	batching() bool
+}
+
+// completion is the outcome of one executed request.
+type completion struct {
+	failed bool
+	// latency is the duration of the client call.
+	latency time.Duration
+	// timedOut reports that the request reached its RequestTimeout.
+	timedOut bool
 }
 
 type typedEndpoint[C any, R any] struct {
 	client    Client[C, R]
 	provider  DataProvider[C]
 	collector Collector[R]
+	// contextCollector is collector when it implements ContextCollector.
+	contextCollector ContextCollector[R]
+	// batch queues results when collector implements BatchCollector.
+	batch *resultBatch[R]
+}
+
+const (
+	// resultBatchSize is the most results handed to a BatchCollector at once.
+	resultBatchSize = 256
+	// resultBatchInterval is how often runs flush partial batches.
+	resultBatchInterval = 10 * time.Millisecond
+)
+
+// resultBatch queues results for a BatchCollector.
+type resultBatch[R any] struct {
+	collector BatchCollector[R]
+	mu        sync.Mutex
+	results   []R
+}
+
+func (b *resultBatch[R]) add(result R) {
+	b.mu.Lock()
+	b.results = append(b.results, result)
+	if len(b.results) < resultBatchSize {
+		b.mu.Unlock()
+		return
+	}
+	full := b.results
+	b.results = make([]R, 0, resultBatchSize)
+	b.mu.Unlock()
+	b.collector.CollectBatch(full)
+}
+
+func (b *resultBatch[R]) flush() {
+	b.mu.Lock()
+	queued := b.results
+	b.results = make([]R, 0, resultBatchSize)
+	b.mu.Unlock()
+	if len(queued) != 0 {
+		b.collector.CollectBatch(queued)
+	}
 }
 
 // NewEndpoint adapts typed request generation, invocation, and result collection
@@ -42,11 +151,184 @@ func NewEndpoint[C any, R any](client Client[C, R], provider DataProvider[C], co
 	if isNil(client) || isNil(provider) || isNil(collector) {
 		return nil, errors.New("client, provider, and collector must be non-nil")
 	}
-	return typedEndpoint[C, R]{client: client, provider: provider, collector: collector}, nil
+	endpoint := typedEndpoint[C, R]{client: client, provider: provider, collector: collector}
+	endpoint.contextCollector, _ = collector.(ContextCollector[R])
+	if batch, ok := collector.(BatchCollector[R]); ok && (endpoint.contextCollector == nil || !needsContext(collector)) {
+		endpoint.batch = &resultBatch[R]{collector: batch, results: make([]R, 0, resultBatchSize)}
+		endpoint.contextCollector = nil
+	}
+	return endpoint, nil
+}
+
+// needsContext reports whether a ContextCollector uses the request context.
+// Built-in collectors that only use it for optional columns say so;
+// others are assumed to need it.
+func needsContext(collector any) bool {
+	if c, ok := collector.(interface{ needsContext() bool }); ok {
+		return c.needsContext()
+	}
+	return true
+}
+
+func (e typedEndpoint[C, R]) execute(ctx context.Context) completion {
+	request := e.provider.GetData()
+	started := time.Now()
+	result := e.client.CallEndpoint(ctx, request)
+	done := completion{latency: time.Since(started)}
+	if outcome, ok := any(result).(Outcome); ok {
+		done.failed = outcome.Failed()
+	}
+	if warm, ok := ctx.Value(warmUpKey{}).(warmUp); ok && !warm.collect {
+		return done
+	}
+	switch {
+	case e.batch != nil:
+		e.batch.add(result)
+	case e.contextCollector != nil:
+		e.contextCollector.CollectContext(ctx, result)
+	default:
+		e.collector.Collect(result)
+	}
+	return done
+}
+
+func (e typedEndpoint[C, R]) flush() {
+	if e.batch != nil {
+		e.batch.flush()
+	}
+}
+
+func (e typedEndpoint[C, R]) batching() bool { return e.batch != nil }
+
+func (e typedEndpoint[C, R]) collectorErr() error {
+	if reporter, ok := e.collector.(ErrorReporter); ok {
+		return reporter.Err()
+	}
+	return nil
+}
+
+type warmUpKey struct{}
+
+type warmUp struct{ collect bool }
+
+type scheduledKey struct{}
+
+type phaseKey struct{}
+
+type workerKey struct{}
+
+type seedKey struct{}
+
+// arrivalContext carries an arrival's scheduled time and phase with a single
+// allocation per batch.
+type arrivalContext struct {
+	context.Context
+	at    time.Time
+	phase *PhaseInfo
+	rps   uint64
+	// user is one more than the virtual user that issued the arrival, or zero
+	// outside closed phases.
+	user int
+	// seed derives from Spec.Seed and the arrival's schedule, for random
+	// draws made on the request's behalf.
+	seed uint64
+	// seeds counts the seeds handed out, so requests sharing the batch's
+	// context draw independently.
+	seeds atomic.Uint64
+}
+
+func (c *arrivalContext) Value(key any) any {
+	switch key {
+	case scheduledKey{}:
+		return c.at
+	case phaseKey{}:
+		info := *c.phase
+		info.RPS = c.rps
+		return info
+	case workerKey{}:
+		if c.user != 0 {
+			return c.user - 1
+		}
+	case seedKey{}:
+		return splitMix64(splitMix64(c.seed+uint64(c.user)) + c.seeds.Add(1) - 1)
+	}
+	return c.Context.Value(key)
+}
+
+// workerContext records the pool worker executing a request.
+type workerContext struct {
+	context.Context
+	worker int
+}
+
+func (c *workerContext) Value(key any) any {
+	if key == (workerKey{}) {
+		return c.worker
+	}
+	return c.Context.Value(key)
+}
+
+// randomFromContext returns a random source seeded for a request, which
+// follows Spec.Seed for requests issued by a workload. Requests of one batch
+// receive distinct seeds, and a batch yields the same seeds in every run,
+// though not necessarily to the same request.
+func randomFromContext(ctx context.Context) phaseRandom {
+	seed, ok := ctx.Value(seedKey{}).(uint64)
+	if !ok {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return phaseRandom{state: splitMix64(seed)}
+}
+
+// PhaseInfo describes the phase that issued a request, so clients can tag
+// outgoing requests and correlate server-side traces with phases.
+type PhaseInfo struct {
+	// Index is the phase's position in Report.Phases, which is its position
+	// in Spec.Phases unless the schedule repeats.
+	Index int
+	// Iteration counts completed repetitions of the schedule before the
+	// phase. It is zero unless Spec.Repeat or RepeatFor is set.
+	Iteration int
+	Name      string
+	// Workload is the Spec.Name of the workload running the phase.
+	Workload string
+	// Kind is how the phase schedules arrivals: "uniform", "poisson",
+	// "closed", "trace", "burst", "pacer", "idle", or the name of a registered
+	// schedule.
+	Kind string
+	// RPS is the phase's offered rate when the request was scheduled, or zero
+	// for schedules without a rate.
+	RPS uint64
+	// Tags are the phase's Tags. They are shared and must not be modified.
+	Tags map[string]string
+}
+
+// PhaseFromContext returns the phase that issued a request.
+func PhaseFromContext(ctx context.Context) (PhaseInfo, bool) {
+	info, ok := ctx.Value(phaseKey{}).(PhaseInfo)
+	return info, ok
+}
+
+// ScheduledAt returns when the workload scheduled a request, as opposed to when
+// the request was sent. Measuring latency from it avoids coordinated omission:
+// delays inside the generator count against the request instead of vanishing.
+func ScheduledAt(ctx context.Context) (time.Time, bool) {
+	at, ok := ctx.Value(scheduledKey{}).(time.Time)
+	return at, ok
+}
+
+// WorkerFromContext returns the virtual user that issued a request in a closed
+// phase, or otherwise the Spec.Workers pool worker executing it, numbered from
+// zero. Requests running on their own goroutine have no worker.
+func WorkerFromContext(ctx context.Context) (int, bool) {
+	worker, ok := ctx.Value(workerKey{}).(int)
+	return worker, ok
 }
 
-func (e typedEndpoint[C, R]) execute(ctx context.Context) {
-	e.collector.Collect(e.client.CallEndpoint(ctx, e.provider.GetData()))
+// IsWarmUp reports whether a request context belongs to a phase warm-up.
+func IsWarmUp(ctx context.Context) bool {
+	_, ok := ctx.Value(warmUpKey{}).(warmUp)
+	return ok
 }
 
 func isNil(value any) bool {
@@ -80,7 +80,8 @@ func main() {
 		return
 	}
 	workload, err := go_loadgen.NewWorkload(go_loadgen.Spec{
-		Duration: 20 * time.Second,
+		Duration:      20 * time.Second,
+		HandleSignals: true,
 		Endpoints: map[string]go_loadgen.Endpoint{
 			"increment": endpoint,
 		},
@@ -105,6 +106,9 @@ func main() {
 		return
 	}
 
-	report := workload.Run(context.Background())
+	report, err := workload.Run(context.Background())
+	if err != nil {
+		fmt.Println("Workload ended early:", err)
+	}
 	fmt.Printf("Finished workload in %s: %+v\n", time.Since(startTime), report)
 }
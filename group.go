@@ -0,0 +1,101 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Group runs several workloads together under one context, for example
+// workloads built separately for different services.
+type Group struct {
+	workloads []*Workload
+}
+
+// GroupReport is the outcome of a group run: the combined counts and each
+// workload's report in group order.
+type GroupReport struct {
+	Counts
+	Workloads []Report
+}
+
+// NewGroup returns a group of workloads.
+func NewGroup(workloads ...*Workload) (*Group, error) {
+	if len(workloads) == 0 {
+		return nil, errors.New("group must contain at least one workload")
+	}
+	for i, workload := range workloads {
+		if workload == nil {
+			return nil, fmt.Errorf("workload %d is nil", i)
+		}
+	}
+	return &Group{workloads: workloads}, nil
+}
+
+// Run runs every workload concurrently and waits for all of them. When one
+// ends early, for example on its error budget or a stop condition, the others
+// are stopped as if ctx were cancelled. Errors reported after a complete
+// schedule, such as from collectors, do not stop them. The error joins the
+// workloads' errors.
+func (g *Group) Run(parent context.Context) (GroupReport, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	report := GroupReport{Workloads: make([]Report, len(g.workloads))}
+	errs := make([]error, len(g.workloads))
+	var workloads sync.WaitGroup
+	for i, workload := range g.workloads {
+		workloads.Go(func() {
+			report.Workloads[i], errs[i] = workload.Run(ctx)
+			if endedEarly(errs[i]) && parent.Err() == nil {
+				cancel()
+			}
+		})
+	}
+	workloads.Wait()
+	for i, err := range errs {
+		if parent.Err() == nil {
+			// Workloads stopped because another one failed keep their other
+			// errors, such as from collectors.
+			err = withoutCancellation(err)
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("workload %d: %w", i, err)
+		} else {
+			errs[i] = nil
+		}
+	}
+	for _, workload := range report.Workloads {
+		report.add(workload.Counts)
+	}
+	return report, errors.Join(errs...)
+}
+
+// endedEarly reports whether err stopped a workload before the end of its
+// schedule.
+func endedEarly(err error) bool {
+	for _, early := range []error{ErrErrorBudgetExceeded, ErrStopCondition, ErrInterrupted, context.Canceled} {
+		if errors.Is(err, early) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutCancellation removes context.Canceled from err, including from the
+// errors it joins.
+func withoutCancellation(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var kept []error
+		for _, err := range joined.Unwrap() {
+			if err = withoutCancellation(err); err != nil {
+				kept = append(kept, err)
+			}
+		}
+		return errors.Join(kept...)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupRunsWorkloadsTogether(t *testing.T) {
+	phase := Phase{Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}
+	first := mustWorkload(t, Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": &countingEndpoint{}}, Phases: []Phase{phase}})
+	second := mustWorkload(t, Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": mustEndpoint(t, lengthClient{}, otherProvider{}, &otherCollector{})}, Phases: []Phase{phase}})
+	group, err := NewGroup(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := group.Run(context.Background())
+	if err != nil || len(report.Workloads) != 2 || report.Issued != report.Workloads[0].Issued+report.Workloads[1].Issued || report.Workloads[1].Issued == 0 {
+		t.Fatalf("report=%+v err=%v, want both workloads combined", report, err)
+	}
+}
+
+func TestGroupStopsOthersWhenOneFails(t *testing.T) {
+	failing := testClient(func(context.Context, testRequest) testResult { return testResult{failed: true} })
+	broken := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, failing, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: time.Second, RPS: 500, ErrorBudget: &ErrorBudget{MaxErrors: 3}, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	healthy := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &brokenCollectorEndpoint{}},
+		Phases:    []Phase{{Duration: time.Second, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	group, err := NewGroup(broken, healthy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := group.Run(context.Background())
+	if !errors.Is(err, ErrErrorBudgetExceeded) || errors.Is(err, context.Canceled) || report.Workloads[1].SchedulingDuration > 500*time.Millisecond {
+		t.Fatalf("err=%v scheduling=%s, want the healthy workload stopped without its own error", err, report.Workloads[1].SchedulingDuration)
+	}
+	if !errors.Is(err, errBrokenCollector) {
+		t.Fatalf("err=%v, want the stopped workload's collector error kept", err)
+	}
+}
+
+func TestGroupKeepsOthersRunningAfterCollectorErrors(t *testing.T) {
+	broken := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &brokenCollectorEndpoint{}},
+		Phases:    []Phase{{Duration: 10 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	healthy := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Duration: 200 * time.Millisecond, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	group, err := NewGroup(broken, healthy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := group.Run(context.Background())
+	if !errors.Is(err, errBrokenCollector) || errors.Is(err, context.Canceled) || report.Workloads[1].SchedulingDuration < 200*time.Millisecond {
+		t.Fatalf("err=%v scheduling=%s, want the other workload to finish its schedule", err, report.Workloads[1].SchedulingDuration)
+	}
+}
+
+var errBrokenCollector = errors.New("disk full")
+
+// brokenCollectorEndpoint reports a collector error after its run.
+type brokenCollectorEndpoint struct{ countingEndpoint }
+
+func (e *brokenCollectorEndpoint) collectorErr() error { return errBrokenCollector }
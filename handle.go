@@ -0,0 +1,91 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned by Handle.Wait after Handle.Stop ended the run early.
+var ErrStopped = errors.New("stopped")
+
+// Handle controls a run started with Start, for embedding the generator in a
+// long-lived service.
+type Handle struct {
+	done   chan struct{}
+	report Report
+	err    error
+
+	mu      sync.Mutex
+	run     *run
+	stopped bool
+}
+
+// Status is a snapshot of a run.
+type Status struct {
+	Counts
+	// Elapsed is the run's schedule time, excluding pauses.
+	Elapsed time.Duration
+	Running bool
+}
+
+// Start runs the workload in the background. It behaves like Run, which the
+// returned handle waits for.
+func (w *Workload) Start(ctx context.Context) *Handle {
+	h := &Handle{done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		h.report, h.err = w.runFrom(ctx, 0, h)
+	}()
+	return h
+}
+
+// started attaches the run and applies a Stop that preceded it.
+func (h *Handle) started(r *run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.run = r
+	if h.stopped {
+		r.abort(ErrStopped)
+	}
+}
+
+// Stop ends scheduling and lets outstanding requests drain. It does not wait;
+// use Wait for the report.
+func (h *Handle) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	h.stopped = true
+	if h.run != nil {
+		h.run.abort(ErrStopped)
+	}
+}
+
+// Wait blocks until the run ends and returns Run's results.
+func (h *Handle) Wait() (Report, error) {
+	<-h.done
+	return h.report, h.err
+}
+
+// Done is closed when the run ends.
+func (h *Handle) Done() <-chan struct{} { return h.done }
+
+// Status reports the run's progress.
+func (h *Handle) Status() Status {
+	select {
+	case <-h.done:
+		return Status{Counts: h.report.Counts, Elapsed: h.report.Checkpoint.Offset}
+	default:
+	}
+	h.mu.Lock()
+	r := h.run
+	h.mu.Unlock()
+	if r == nil {
+		return Status{Running: true}
+	}
+	return Status{Counts: r.counts(), Elapsed: r.elapsed(), Running: true}
+}
@@ -0,0 +1,45 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartReturnsControllableHandle(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Duration: time.Second, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	handle := workload.Start(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	status := handle.Status()
+	if !status.Running || status.Issued == 0 || status.Elapsed < 30*time.Millisecond {
+		t.Fatalf("status=%+v, want a running snapshot", status)
+	}
+	handle.Stop()
+	report, err := handle.Wait()
+	if !errors.Is(err, ErrStopped) || report.SchedulingDuration > 500*time.Millisecond || report.Issued < status.Issued {
+		t.Fatalf("err=%v scheduling=%s issued=%d, want the run stopped early", err, report.SchedulingDuration, report.Issued)
+	}
+	if final := handle.Status(); final.Running || final.Issued != report.Issued {
+		t.Fatalf("status=%+v, want the final counts", final)
+	}
+	<-handle.Done()
+}
+
+func TestStopBeforeRunStartsStopsIt(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Duration: time.Second, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	handle := workload.Start(context.Background())
+	handle.Stop()
+	if _, err := handle.Wait(); !errors.Is(err, ErrStopped) {
+		t.Fatalf("err=%v, want the run stopped", err)
+	}
+}
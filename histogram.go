@@ -0,0 +1,110 @@
+package go_loadgen
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHistogramSubBits splits every power of two into 16 buckets, bounding
+// the relative error of a percentile to about 3%.
+const defaultHistogramSubBits = 4
+
+// histogram is a lock-free log-linear latency histogram. Recording is one
+// atomic add, so every request can be recorded without sampling. Each power
+// of two is split into 2^subBits buckets.
+type histogram struct {
+	subBits int
+	counts  []atomic.Uint64
+}
+
+func newHistogram(subBits int) histogram {
+	return histogram{subBits: subBits, counts: make([]atomic.Uint64, (64-subBits+1)<<subBits)}
+}
+
+// histogramSubBits returns the sub-bucket bits that bound the relative error
+// of a bucket midpoint to 10^-digits.
+func histogramSubBits(digits int) int {
+	return int(math.Ceil(float64(digits)*math.Log2(10))) - 1
+}
+
+func histogramIndex(subBits int, value uint64) int {
+	subBuckets := uint64(1) << subBits
+	if value < subBuckets {
+		return int(value)
+	}
+	exponent := bits.Len64(value) - 1
+	sub := (value >> (exponent - subBits)) & (subBuckets - 1)
+	return (exponent-subBits+1)<<subBits + int(sub)
+}
+
+// histogramValue returns the midpoint of a bucket.
+func histogramValue(subBits int, index int) uint64 {
+	subBuckets := 1 << subBits
+	if index < subBuckets {
+		return uint64(index)
+	}
+	exponent := index>>subBits + subBits - 1
+	sub := uint64(index % subBuckets)
+	width := uint64(1) << (exponent - subBits)
+	return (uint64(subBuckets)+sub)*width + width/2
+}
+
+func (h *histogram) record(latency time.Duration) {
+	h.counts[histogramIndex(h.subBits, uint64(max(latency, 0)))].Add(1)
+}
+
+func (h *histogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+}
+
+// latencies is a snapshot of one or more histograms of the same precision.
+type latencies struct {
+	subBits int
+	counts  []uint64
+	total   uint64
+}
+
+func (l *latencies) add(h *histogram) {
+	if l.counts == nil {
+		l.subBits, l.counts = h.subBits, make([]uint64, len(h.counts))
+	}
+	for i := range h.counts {
+		count := h.counts[i].Load()
+		l.counts[i] += count
+		l.total += count
+	}
+}
+
+// take adds h to the snapshot and clears it. Samples recorded meanwhile are
+// kept in h rather than lost.
+func (l *latencies) take(h *histogram) {
+	if l.counts == nil {
+		l.subBits, l.counts = h.subBits, make([]uint64, len(h.counts))
+	}
+	for i := range h.counts {
+		count := h.counts[i].Swap(0)
+		l.counts[i] += count
+		l.total += count
+	}
+}
+
+// percentile returns the latency below which percentile percent of samples fall.
+func (l *latencies) percentile(percentile float64) time.Duration {
+	if l.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(percentile / 100 * float64(l.total)))
+	rank = min(max(rank, 1), l.total)
+	var seen uint64
+	for i, count := range l.counts {
+		seen += count
+		if seen >= rank {
+			return time.Duration(histogramValue(l.subBits, i))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,222 @@
+package go_loadgen
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTMLReport records latency and throughput over time and renders them, with
+// a run's Report, as a self-contained HTML page that can be shared without
+// the raw results. Pass it in Spec.Observers and call Write after Run.
+type HTMLReport struct {
+	title    string
+	interval time.Duration
+	mu       sync.Mutex
+	started  time.Time
+	windows  []htmlWindow
+	// current is the window samples are added to; its latencies are
+	// summarized into windows when a later window starts.
+	current htmlWindow
+	digest  tdigest
+	targets map[int]uint64
+}
+
+type htmlWindow struct {
+	requests      uint64
+	failed        uint64
+	target        uint64
+	p50, p95, p99 float64
+}
+
+// NewHTMLReport creates a report titled title that plots one point per
+// interval, one second when interval is not positive.
+func NewHTMLReport(title string, interval time.Duration) *HTMLReport {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &HTMLReport{title: title, interval: interval, digest: newTDigest(100), targets: make(map[int]uint64)}
+}
+
+// Observe implements Observer.
+func (h *HTMLReport) Observe(sample Sample) {
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.started.IsZero() {
+		h.started = now
+	}
+	for int(now.Sub(h.started)/h.interval) > len(h.windows) {
+		h.closeWindow()
+	}
+	h.current.requests++
+	if sample.Failed {
+		h.current.failed++
+	}
+	h.digest.add(float64(sample.Latency) / float64(time.Millisecond))
+	h.targets[sample.Phase.Index] = sample.Phase.RPS
+}
+
+// closeWindow summarizes the current window and starts the next. h.mu must
+// be held.
+func (h *HTMLReport) closeWindow() {
+	window := h.current
+	if window.requests != 0 {
+		window.p50, window.p95, window.p99 = h.digest.quantile(0.5), h.digest.quantile(0.95), h.digest.quantile(0.99)
+	}
+	for _, rps := range h.targets {
+		window.target += rps
+	}
+	h.windows = append(h.windows, window)
+	h.current = htmlWindow{}
+	h.digest.reset()
+	clear(h.targets)
+}
+
+// Write renders the report with the counts and thresholds of report.
+func (h *HTMLReport) Write(w io.Writer, report Report) error {
+	h.mu.Lock()
+	windows := h.windows
+	if h.current.requests != 0 {
+		h.closeWindow()
+		windows = h.windows
+	}
+	windows = append([]htmlWindow(nil), windows...)
+	h.mu.Unlock()
+
+	seconds := h.interval.Seconds()
+	latency := []chartSeries{{Name: "p50", Color: "#2b8a3e"}, {Name: "p95", Color: "#e67700"}, {Name: "p99", Color: "#c92a2a"}}
+	throughput := []chartSeries{{Name: "achieved", Color: "#1864ab"}, {Name: "failed", Color: "#c92a2a"}, {Name: "target", Color: "#868e96"}}
+	var targeted bool
+	for _, window := range windows {
+		latency[0].Values = append(latency[0].Values, window.p50)
+		latency[1].Values = append(latency[1].Values, window.p95)
+		latency[2].Values = append(latency[2].Values, window.p99)
+		throughput[0].Values = append(throughput[0].Values, float64(window.requests)/seconds)
+		throughput[1].Values = append(throughput[1].Values, float64(window.failed)/seconds)
+		throughput[2].Values = append(throughput[2].Values, float64(window.target))
+		targeted = targeted || window.target != 0
+	}
+	if !targeted {
+		throughput = throughput[:2]
+	}
+	data := htmlData{
+		Title:      h.title,
+		Generated:  time.Now().Format(time.RFC1123),
+		Report:     report,
+		Latency:    newChart("latency (ms)", h.interval, latency),
+		Throughput: newChart("requests per second", h.interval, throughput),
+	}
+	for _, phase := range report.Phases {
+		row := htmlPhase{PhaseReport: phase}
+		if phase.Completed != 0 {
+			row.ErrorRate = 100 * float64(phase.Failed) / float64(phase.Completed)
+		}
+		data.Phases = append(data.Phases, row)
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+type htmlData struct {
+	Title      string
+	Generated  string
+	Report     Report
+	Phases     []htmlPhase
+	Latency    chart
+	Throughput chart
+}
+
+type htmlPhase struct {
+	PhaseReport
+	ErrorRate float64
+}
+
+type chartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+	Points string
+}
+
+// chart is a line chart laid out for a 720 by 240 SVG viewport.
+type chart struct {
+	Title    string
+	Series   []chartSeries
+	YMax     string
+	Duration time.Duration
+}
+
+func newChart(title string, interval time.Duration, series []chartSeries) chart {
+	c := chart{Title: title, Series: series}
+	var top float64
+	var points int
+	for _, s := range series {
+		points = max(points, len(s.Values))
+		for _, value := range s.Values {
+			top = max(top, value)
+		}
+	}
+	c.Duration = interval * time.Duration(points)
+	if top == 0 {
+		top = 1
+	}
+	c.YMax = fmt.Sprintf("%.4g", top)
+	for i := range c.Series {
+		var b strings.Builder
+		for j, value := range c.Series[i].Values {
+			x := 40 + 670*float64(j)/float64(max(points-1, 1))
+			y := 220 - 200*value/top
+			fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+		}
+		c.Series[i].Points = b.String()
+	}
+	return c
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #212529; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #dee2e6; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.failed { color: #c92a2a; font-weight: bold; }
+svg { border: 1px solid #dee2e6; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}}. Ran for {{.Report.Duration}}, of which {{.Report.SchedulingDuration}} scheduling.</p>
+<table>
+<tr><th>Scheduled</th><th>Issued</th><th>Completed</th><th>Failed</th><th>Timed out</th><th>Dropped</th><th>Missed</th><th>Mean lag</th><th>Max lag</th></tr>
+<tr><td>{{.Report.Scheduled}}</td><td>{{.Report.Issued}}</td><td>{{.Report.Completed}}</td><td>{{.Report.Failed}}</td><td>{{.Report.TimedOut}}</td><td>{{.Report.Dropped}}</td><td>{{.Report.Missed}}</td><td>{{.Report.MeanLag}}</td><td>{{.Report.MaxLag}}</td></tr>
+</table>
+{{template "chart" .Latency}}
+{{template "chart" .Throughput}}
+<h2>Phases</h2>
+<table>
+<tr><th>Phase</th><th>Scheduled</th><th>Issued</th><th>Completed</th><th>Failed</th><th>Error rate</th><th>Timed out</th><th>Dropped</th><th>Missed</th></tr>
+{{range $i, $p := .Phases}}<tr><td>{{if $p.Name}}{{$p.Name}}{{else}}phase {{$i}}{{end}}</td><td>{{$p.Scheduled}}</td><td>{{$p.Issued}}</td><td>{{$p.Completed}}</td><td>{{$p.Failed}}</td><td>{{printf "%.2f%%" $p.ErrorRate}}</td><td>{{$p.TimedOut}}</td><td>{{$p.Dropped}}</td><td>{{$p.Missed}}</td></tr>
+{{end}}</table>
+{{with .Report.Thresholds}}<h2>Thresholds</h2>
+<table>
+<tr><th>Threshold</th><th>Result</th><th>Latency</th><th>Error rate</th><th>Throughput</th></tr>
+{{range .}}<tr><td>{{.Threshold}}</td><td{{if not .Passed}} class="failed"{{end}}>{{if .Passed}}passed{{else}}failed{{end}}</td><td>{{.Latency}}</td><td>{{printf "%.4f" .ErrorRate}}</td><td>{{printf "%.1f" .Throughput}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+{{define "chart"}}<h2>{{.Title}}</h2>
+<svg viewBox="0 0 720 240" width="720" height="240" xmlns="http://www.w3.org/2000/svg">
+<line x1="40" y1="220" x2="710" y2="220" stroke="#adb5bd"/><line x1="40" y1="20" x2="40" y2="220" stroke="#adb5bd"/>
+<text x="36" y="24" font-size="10" text-anchor="end">{{.YMax}}</text><text x="36" y="220" font-size="10" text-anchor="end">0</text>
+<text x="710" y="234" font-size="10" text-anchor="end">{{.Duration}}</text>
+{{range .Series}}<polyline fill="none" stroke="{{.Color}}" stroke-width="1.5" points="{{.Points}}"><title>{{.Name}}</title></polyline>
+{{end}}</svg>
+<p>{{range .Series}}<span style="color: {{.Color}}">&#9632; {{.Name}}</span> {{end}}</p>
+{{end}}`))
@@ -0,0 +1,41 @@
+package go_loadgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLReportRendersRun(t *testing.T) {
+	report := NewHTMLReport("checkout <release>", 10*time.Millisecond)
+	workload := mustWorkload(t, Spec{
+		Duration:   time.Second,
+		Endpoints:  map[string]Endpoint{"one": mustEndpoint(t, testClient(func(context.Context, testRequest) testResult { return testResult{} }), testProvider{}, &testCollector{})},
+		Observers:  []Observer{report},
+		Thresholds: []Threshold{{MaxLatency: 200 * time.Millisecond}, {MaxErrorRate: 0.01, MinThroughput: 1e6}},
+		Phases:     []Phase{{Name: "steady", Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	result := mustRun(t, workload)
+
+	var page strings.Builder
+	if err := report.Write(&page, result); err != nil {
+		t.Fatal(err)
+	}
+	html := page.String()
+	for _, want := range []string{
+		"<title>checkout &lt;release&gt;</title>",
+		"<td>steady</td>",
+		"<title>target</title>",
+		"<td>p99 &lt;= 200ms</td>",
+		"<td>error rate &lt;= 0.01, throughput &gt;= 1000000 rps</td>",
+		`class="failed"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("report is missing %q:\n%s", want, html)
+		}
+	}
+	if points := strings.Count(html, "<polyline"); points != 6 {
+		t.Fatalf("rendered %d series, want three latency and three throughput series", points)
+	}
+}
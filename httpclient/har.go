@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// HAR is a recorded browser session, read from an HTTP Archive file with
+// ReadHAR, ready to replay through a Client:
+//
+//	session, err := httpclient.ReadHAR(file, "shop.example.com")
+//	session.Rehost("https://staging.example.com")
+//	endpoint, err := go_loadgen.NewEndpoint[httpclient.Request, httpclient.Result](client, session.Provider(), collector)
+//	phase := session.Phase(go_loadgen.Target{Endpoint: "session", Weight: 1})
+type HAR struct {
+	// Requests are the session's requests in the order they started.
+	Requests []Request
+	// Offsets are when each request started, relative to the first.
+	Offsets []time.Duration
+}
+
+// harFile is the subset of the HAR 1.2 format that replay needs.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Request         struct {
+		Method  string `json:"method"`
+		URL     string `json:"url"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		PostData *struct {
+			Text string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+}
+
+// replayedHeader reports whether a recorded header is sent on replay. Pseudo
+// headers, framing headers, and Host are set by the transport instead, so a
+// rehosted session does not carry the recorded host.
+func replayedHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Content-Length", "Connection", "Keep-Alive", "Transfer-Encoding", "Upgrade":
+		return false
+	}
+	return !strings.HasPrefix(name, ":")
+}
+
+// ReadHAR parses a HAR file exported from a browser's developer tools. With
+// hosts, only requests to those hosts are kept, dropping third-party assets
+// and analytics. Request bodies are taken from postData text; recorded
+// responses are ignored.
+func ReadHAR(reader io.Reader, hosts ...string) (*HAR, error) {
+	var file harFile
+	if err := json.NewDecoder(reader).Decode(&file); err != nil {
+		return nil, fmt.Errorf("decode HAR: %w", err)
+	}
+	entries := file.Log.Entries
+	slices.SortStableFunc(entries, func(a, b harEntry) int { return a.StartedDateTime.Compare(b.StartedDateTime) })
+	session := &HAR{}
+	var first time.Time
+	for i, entry := range entries {
+		target, err := url.Parse(entry.Request.URL)
+		if err != nil || !target.IsAbs() {
+			return nil, fmt.Errorf("HAR entry %d: invalid URL %q", i, entry.Request.URL)
+		}
+		if len(hosts) != 0 && !slices.Contains(hosts, target.Hostname()) {
+			continue
+		}
+		request := Request{Method: entry.Request.Method, URL: entry.Request.URL}
+		for _, header := range entry.Request.Headers {
+			if replayedHeader(header.Name) {
+				if request.Header == nil {
+					request.Header = make(http.Header)
+				}
+				request.Header.Add(header.Name, header.Value)
+			}
+		}
+		if entry.Request.PostData != nil {
+			request.Body = []byte(entry.Request.PostData.Text)
+		}
+		if len(session.Requests) == 0 {
+			first = entry.StartedDateTime
+		}
+		session.Requests = append(session.Requests, request)
+		session.Offsets = append(session.Offsets, entry.StartedDateTime.Sub(first))
+	}
+	if len(session.Requests) == 0 {
+		return nil, errors.New("HAR has no requests to replay")
+	}
+	return session, nil
+}
+
+// Rehost points every request at base's scheme and host, keeping recorded
+// paths and queries, to replay a production session against staging.
+func (h *HAR) Rehost(base string) error {
+	target, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	if !target.IsAbs() || target.Host == "" {
+		return fmt.Errorf("base URL %q must have a scheme and host", base)
+	}
+	for i := range h.Requests {
+		recorded, err := url.Parse(h.Requests[i].URL)
+		if err != nil {
+			return err
+		}
+		recorded.Scheme, recorded.Host = target.Scheme, target.Host
+		h.Requests[i].URL = recorded.String()
+	}
+	return nil
+}
+
+// Provider returns a data provider that hands out the requests in recorded
+// order, starting over after the last. Paired with Phase, the Nth arrival
+// sends the Nth request.
+func (h *HAR) Provider() go_loadgen.DataProvider[Request] {
+	return &harProvider{requests: h.Requests}
+}
+
+// Phase returns a phase that replays the session's timing, sending every
+// arrival to targets. It lasts until a second after the last request.
+func (h *HAR) Phase(targets ...go_loadgen.Target) go_loadgen.Phase {
+	return go_loadgen.Phase{
+		Duration: h.Offsets[len(h.Offsets)-1] + time.Second,
+		Trace:    slices.Clone(h.Offsets),
+		Targets:  targets,
+	}
+}
+
+type harProvider struct {
+	requests []Request
+	next     atomic.Uint64
+}
+
+func (p *harProvider) GetData() Request {
+	return p.requests[(p.next.Add(1)-1)%uint64(len(p.requests))]
+}
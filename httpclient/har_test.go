@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+const session = `{"log": {"version": "1.2", "entries": [
+	{"startedDateTime": "2026-03-01T10:00:00.150Z", "request": {"method": "POST", "url": "https://shop.example.com/cart?item=7",
+		"headers": [{"name": ":authority", "value": "shop.example.com"}, {"name": "Content-Type", "value": "application/json"}, {"name": "Content-Length", "value": "9"}],
+		"postData": {"mimeType": "application/json", "text": "{\"qty\":1}"}}},
+	{"startedDateTime": "2026-03-01T10:00:00.000Z", "request": {"method": "GET", "url": "https://shop.example.com/",
+		"headers": [{"name": "Host", "value": "shop.example.com"}, {"name": "Cookie", "value": "session=abc"}]}},
+	{"startedDateTime": "2026-03-01T10:00:00.050Z", "request": {"method": "GET", "url": "https://analytics.example.net/collect", "headers": []}}
+]}}`
+
+func TestHARReplaysSession(t *testing.T) {
+	replay, err := ReadHAR(strings.NewReader(session), "shop.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{0, 150 * time.Millisecond}; !slices.Equal(replay.Offsets, want) {
+		t.Fatalf("offsets=%v, want %v", replay.Offsets, want)
+	}
+
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Cookie")+r.Header.Get("Content-Type")+" "+string(body))
+	}))
+	defer server.Close()
+	if err := replay.Rehost(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	client, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := go_loadgen.NewEndpoint[Request, Result](client, replay.Provider(), &resultCollector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phase := replay.Phase(go_loadgen.Target{Endpoint: "session", Weight: 1})
+	workload, err := go_loadgen.NewWorkload(go_loadgen.Spec{
+		Duration: phase.Duration,
+		// A coarse resolution keeps a late wake-up from missing an arrival.
+		Resolution: 50 * time.Millisecond,
+		Endpoints:  map[string]go_loadgen.Endpoint{"session": endpoint},
+		Phases:     []go_loadgen.Phase{phase},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err := workload.Run(context.Background()); err != nil || report.Completed != 2 || report.Failed != 0 {
+		t.Fatalf("report=%+v err=%v, want the session replayed", report, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"GET / session=abc ", `POST /cart?item=7 application/json {"qty":1}`}
+	if !slices.Equal(seen, want) {
+		t.Fatalf("requests=%q, want %q", seen, want)
+	}
+
+	if _, err := ReadHAR(strings.NewReader(session), "other.example.com"); err == nil {
+		t.Fatal("expected a HAR without matching requests to be rejected")
+	}
+}
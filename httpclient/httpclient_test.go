@@ -0,0 +1,356 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			w.Header().Set("X-Seen", r.Method+" "+r.Host+" "+r.Header.Get("X-Token")+" "+r.Header.Get("X-Env"))
+			w.Write(append([]byte("echo:"), body...))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientMeasuresCalls(t *testing.T) {
+	server := newServer(t)
+	client, err := New(Config{
+		Method: http.MethodPost,
+		URL:    server.URL + "/api/",
+		Header: http.Header{"X-Token": {"default"}, "X-Env": {"test"}},
+		Body:   []byte("default"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	result := client.CallEndpoint(context.Background(), Request{URL: "items", Header: http.Header{"X-Token": {"override"}}, Body: []byte("hello")})
+	if result.Failed() || result.StatusCode != http.StatusOK || result.Method != http.MethodPost || result.URL != server.URL+"/api/items" {
+		t.Fatalf("result=%+v, want a successful POST to the resolved URL", result)
+	}
+	if result.BytesSent != 5 || result.BytesReceived != 10 || result.Latency <= 0 {
+		t.Fatalf("result=%+v, want body sizes and a latency", result)
+	}
+
+	result = client.CallEndpoint(context.Background(), Request{Method: http.MethodGet, URL: "/missing"})
+	if !result.Failed() || result.Class != ClassClientError || result.StatusCode != http.StatusNotFound || result.BytesSent != 7 {
+		t.Fatalf("result=%+v, want a 4xx failure with the default body", result)
+	}
+	if result = client.CallEndpoint(context.Background(), Request{URL: "/broken"}); result.Class != ClassServerError {
+		t.Fatalf("result=%+v, want a 5xx failure", result)
+	}
+	record := result.CSVRecord()
+	if len(record) != len(result.CSVHeaders()) || record[2] != "502" || record[10] != "5xx" {
+		t.Fatalf("record=%v, want status and class columns", record)
+	}
+}
+
+func TestClientHeadersOverrideHost(t *testing.T) {
+	server := newServer(t)
+	var seen string
+	client, err := New(Config{URL: server.URL, Header: http.Header{"Host": {"example.test"}, "X-Env": {"prod"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.client.Transport
+	client.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		response, err := transport.RoundTrip(r)
+		if err == nil {
+			seen = response.Header.Get("X-Seen")
+		}
+		return response, err
+	})
+	if result := client.CallEndpoint(context.Background(), Request{}); result.Failed() {
+		t.Fatalf("result=%+v", result)
+	}
+	if seen != "GET example.test  prod" {
+		t.Fatalf("seen=%q, want the Host header applied", seen)
+	}
+}
+
+func TestClientClassifiesTransportErrors(t *testing.T) {
+	server := newServer(t)
+	client, err := New(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if result := client.CallEndpoint(ctx, Request{URL: "/slow"}); result.Class != ClassTimeout {
+		t.Fatalf("result=%+v, want a timeout", result)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if result := client.CallEndpoint(ctx, Request{}); result.Class != ClassCanceled {
+		t.Fatalf("result=%+v, want a cancellation", result)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if result := client.CallEndpoint(context.Background(), Request{URL: closed.URL}); result.Class != ClassConnect {
+		t.Fatalf("result=%+v, want a connect failure", result)
+	}
+	if result := client.CallEndpoint(context.Background(), Request{URL: "http://host.invalid/"}); result.Class != ClassDNS {
+		t.Fatalf("result=%+v, want a DNS failure", result)
+	}
+	unbased, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := unbased.CallEndpoint(context.Background(), Request{}); result.Class != ClassOther || !strings.Contains(result.Error, "no URL") {
+		t.Fatalf("result=%+v, want a missing URL reported", result)
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	var connections atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := New(Config{URL: server.URL, MaxConnsPerHost: 8, DisableKeepAlives: true, DisableHTTP2: true, DisableCompression: true, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 1024 || transport.MaxConnsPerHost != 8 || !transport.DisableCompression || transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Fatalf("transport=%+v, want the configured limits", transport)
+	}
+	for range 3 {
+		if result := client.CallEndpoint(context.Background(), Request{}); result.Failed() {
+			t.Fatalf("result=%+v", result)
+		}
+	}
+	if got := connections.Load(); got != 3 {
+		t.Fatalf("connections=%d, want one per request without keep-alives", got)
+	}
+	if _, err := New(Config{DialTimeout: -1}); err == nil {
+		t.Fatal("expected a negative timeout to be rejected")
+	}
+}
+
+func TestClientVerifiesTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	client, err := New(Config{URL: server.URL, TLS: &tls.Config{RootCAs: roots, ServerName: "example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := client.CallEndpoint(context.Background(), Request{}); result.Failed() {
+		t.Fatalf("result=%+v, want the custom root trusted", result)
+	}
+	client, err = New(Config{URL: server.URL, TLS: &tls.Config{RootCAs: roots, ServerName: "wrong.test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := client.CallEndpoint(context.Background(), Request{}); result.Class != ClassTLS {
+		t.Fatalf("result=%+v, want a TLS failure", result)
+	}
+}
+
+func TestClientRecordsConnectionSetup(t *testing.T) {
+	var resumed atomic.Int64
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS.DidResume {
+			resumed.Add(1)
+		}
+	}))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: "example.com"}
+
+	pooled, err := New(Config{URL: server.URL, TLS: tlsConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := pooled.CallEndpoint(context.Background(), Request{})
+	second := pooled.CallEndpoint(context.Background(), Request{})
+	if first.Failed() || first.Reused || first.Connect <= 0 || first.TLSHandshake <= 0 {
+		t.Fatalf("first=%+v, want a new connection with its setup timed", first)
+	}
+	if second.Failed() || !second.Reused || second.Connect != 0 || second.TLSHandshake != 0 {
+		t.Fatalf("second=%+v, want the pooled connection reused", second)
+	}
+
+	fresh, err := New(Config{URL: server.URL, TLS: tlsConfig, DisableKeepAlives: true, ResumeTLSSessions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		result := fresh.CallEndpoint(context.Background(), Request{})
+		if result.Failed() || result.Reused || result.Connect <= 0 || result.TLSHandshake <= 0 {
+			t.Fatalf("result=%+v, want a new connection per request", result)
+		}
+	}
+	if got := resumed.Load(); got == 0 {
+		t.Fatal("expected later connections to resume the TLS session")
+	}
+}
+
+func TestClientControlsResolution(t *testing.T) {
+	server := newServer(t)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	pinned, err := New(Config{URL: "http://backend.test:" + port, PinnedIP: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := pinned.CallEndpoint(context.Background(), Request{}); result.Failed() || result.DNS != 0 {
+		t.Fatalf("result=%+v, want the pinned IP dialed without resolving", result)
+	}
+
+	resolved, err := New(Config{URL: "http://localhost:" + port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := resolved.CallEndpoint(context.Background(), Request{}); result.Failed() || result.DNS <= 0 {
+		t.Fatalf("result=%+v, want the lookup timed", result)
+	}
+
+	var queries atomic.Int64
+	resolver := &net.Resolver{PreferGo: true, Dial: func(context.Context, string, string) (net.Conn, error) {
+		queries.Add(1)
+		return nil, errors.New("resolver unreachable")
+	}}
+	custom, err := New(Config{URL: "http://backend.test:" + port, Resolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := custom.CallEndpoint(context.Background(), Request{}); result.Class != ClassDNS || queries.Load() == 0 {
+		t.Fatalf("result=%+v queries=%d, want the custom resolver queried", result, queries.Load())
+	}
+	if _, err := New(Config{PinnedIP: "backend.test"}); err == nil {
+		t.Fatal("expected a pinned host name to be rejected")
+	}
+}
+
+func TestClientRotatesProxies(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	newProxy := func(name string) *httptest.Server {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			// A forward proxy receives the absolute target URL.
+			seen[name] = append(seen[name], r.URL.String()+" "+r.Header.Get("Proxy-Authorization"))
+		}))
+		t.Cleanup(proxy.Close)
+		return proxy
+	}
+	east, west := newProxy("east"), newProxy("west")
+	westURL := strings.Replace(west.URL, "http://", "http://user:secret@", 1)
+
+	client, err := New(Config{URL: "http://backend.test", Proxies: []string{east.URL, westURL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		if result := client.CallEndpoint(context.Background(), Request{URL: "/orders"}); result.Failed() || result.Proxy == "" || strings.Contains(result.Proxy, "secret") {
+			t.Fatalf("result=%+v, want a proxied call with the proxy redacted", result)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen["east"]) != 2 || len(seen["west"]) != 2 || seen["east"][0] != "http://backend.test/orders " || !strings.HasPrefix(seen["west"][0], "http://backend.test/orders Basic ") {
+		t.Fatalf("seen=%v, want calls rotated over both proxies", seen)
+	}
+	if _, err := New(Config{Proxies: []string{"ftp://proxy.test"}}); err == nil {
+		t.Fatal("expected an unsupported proxy scheme to be rejected")
+	}
+	if _, err := New(Config{Proxies: []string{east.URL}, PinnedIP: "127.0.0.1"}); err == nil {
+		t.Fatal("expected proxies with a pinned IP to be rejected")
+	}
+	if _, err := New(Config{Proxies: []string{east.URL}, Transport: http.DefaultTransport}); err == nil {
+		t.Fatal("expected proxies with a custom transport to be rejected")
+	}
+}
+
+func TestClientRunsInWorkload(t *testing.T) {
+	server := newServer(t)
+	client, err := New(Config{URL: server.URL, FromSchedule: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &resultCollector{}
+	endpoint, err := go_loadgen.NewEndpoint[Request, Result](client, requestProvider{}, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload, err := go_loadgen.NewWorkload(go_loadgen.Spec{
+		Duration:  50 * time.Millisecond,
+		Endpoints: map[string]go_loadgen.Endpoint{"api": endpoint},
+		Phases:    []go_loadgen.Phase{{Duration: 50 * time.Millisecond, RPS: 100, Targets: []go_loadgen.Target{{Endpoint: "api", Weight: 1}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := workload.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if report.Completed == 0 || uint64(len(collector.results)) != report.Completed || report.Failed != 0 {
+		t.Fatalf("report=%+v results=%d, want every call collected and successful", report, len(collector.results))
+	}
+}
+
+type requestProvider struct{}
+
+func (requestProvider) GetData() Request { return Request{URL: "/ping"} }
+
+type resultCollector struct {
+	mu      sync.Mutex
+	results []Result
+}
+
+func (c *resultCollector) Collect(result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, result)
+}
+
+func (c *resultCollector) Close() {}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
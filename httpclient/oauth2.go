@@ -0,0 +1,188 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// expiryDelta is how long before its expiry a token is refreshed, so calls in
+// flight do not present an expired token.
+const expiryDelta = 10 * time.Second
+
+// OAuth2Config describes how OAuth2 obtains bearer tokens from an
+// authorization server.
+type OAuth2Config struct {
+	// TokenURL is the server's token endpoint.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Username and Password select the resource owner password grant.
+	// Without them, the client credentials grant is used.
+	Username string
+	Password string
+	Scopes   []string
+	// Client sends token requests. Nil uses http.DefaultClient, so token
+	// traffic does not share the load's connection pool.
+	Client *http.Client
+}
+
+// OAuth2 obtains and caches bearer tokens and injects them into requests
+// through Middleware. Tokens are refreshed shortly before they expire, with
+// the server's refresh token when it issued one, and whenever the target
+// rejects one with 401 Unauthorized. It is safe for concurrent use, and
+// concurrent calls share one token.
+type OAuth2 struct {
+	config OAuth2Config
+
+	mu      sync.Mutex
+	token   string
+	refresh string
+	expiry  time.Time
+}
+
+// NewOAuth2 creates a token source from config. The first token is fetched by
+// the first call.
+func NewOAuth2(config OAuth2Config) (*OAuth2, error) {
+	if config.TokenURL == "" {
+		return nil, errors.New("token URL must be set")
+	}
+	if _, err := url.Parse(config.TokenURL); err != nil {
+		return nil, fmt.Errorf("parse token URL: %w", err)
+	}
+	if (config.Username == "") != (config.Password == "") {
+		return nil, errors.New("username and password must be set together")
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &OAuth2{config: config}, nil
+}
+
+// Middleware returns a client middleware that sets each request's
+// Authorization header. A call rejected with 401 is retried once with a new
+// token. Calls for which no token can be obtained are not sent and fail with
+// ClassAuth.
+func (o *OAuth2) Middleware() go_loadgen.ClientMiddleware[Request, Result] {
+	return func(next go_loadgen.Client[Request, Result]) go_loadgen.Client[Request, Result] {
+		return go_loadgen.ClientFunc[Request, Result](func(ctx context.Context, request Request) Result {
+			var result Result
+			for attempt := 0; attempt < 2; attempt++ {
+				token, err := o.Token(ctx)
+				if err != nil {
+					return Result{Method: request.Method, URL: request.URL}.fail(ClassAuth, err)
+				}
+				authorized := request
+				authorized.Header = request.Header.Clone()
+				if authorized.Header == nil {
+					authorized.Header = make(http.Header)
+				}
+				authorized.Header.Set("Authorization", "Bearer "+token)
+				result = next.CallEndpoint(ctx, authorized)
+				if result.StatusCode != http.StatusUnauthorized {
+					break
+				}
+				o.invalidate(token)
+			}
+			return result
+		})
+	}
+}
+
+// Token returns a valid access token, fetching one if none is cached or the
+// cached one expires soon.
+func (o *OAuth2) Token(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token != "" && (o.expiry.IsZero() || time.Until(o.expiry) > expiryDelta) {
+		return o.token, nil
+	}
+	form := url.Values{}
+	if o.refresh != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", o.refresh)
+		if err := o.fetch(ctx, form); err == nil {
+			return o.token, nil
+		}
+		// Refresh tokens expire and get revoked; fall back to the grant.
+		o.refresh = ""
+		form = url.Values{}
+	}
+	if o.config.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", o.config.Username)
+		form.Set("password", o.config.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(o.config.Scopes) != 0 {
+		form.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+	if err := o.fetch(ctx, form); err != nil {
+		o.token = ""
+		return "", err
+	}
+	return o.token, nil
+}
+
+// invalidate drops token unless another call has already replaced it.
+func (o *OAuth2) invalidate(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token == token {
+		o.token = ""
+	}
+}
+
+// fetch requests a token with form and caches it. o.mu must be held.
+func (o *OAuth2) fetch(ctx context.Context, form url.Values) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if o.config.ClientID != "" {
+		request.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+	response, err := o.config.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("fetch token: %w", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("fetch token: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch token: %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("decode token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return errors.New("token response has no access token")
+	}
+	o.token, o.expiry = token.AccessToken, time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" {
+		o.refresh = token.RefreshToken
+	}
+	return nil
+}
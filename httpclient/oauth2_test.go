@@ -0,0 +1,81 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+func TestOAuth2InjectsAndRefreshesTokens(t *testing.T) {
+	var mu sync.Mutex
+	var issued int
+	var grants []string
+	current := ""
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		grants = append(grants, r.Form.Get("grant_type"))
+		if id != "loadgen" || secret != "s3cret" || (r.Form.Get("grant_type") == "password" && r.Form.Get("password") != "hunter2") {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		issued++
+		current = fmt.Sprintf("token-%d", issued)
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer","expires_in":3600,"refresh_token":"refresh-%d"}`, current, issued)
+	}))
+	defer tokens.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer "+current {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	auth, err := NewOAuth2(OAuth2Config{TokenURL: tokens.URL, ClientID: "loadgen", ClientSecret: "s3cret", Username: "alice", Password: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := New(Config{URL: api.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := go_loadgen.Chain[Request, Result](base, auth.Middleware())
+	for range 3 {
+		if result := client.CallEndpoint(context.Background(), Request{}); result.Failed() {
+			t.Fatalf("result=%+v, want an authorized call", result)
+		}
+	}
+	// The server revokes the token; the next call is retried with a new one.
+	mu.Lock()
+	current = "revoked"
+	mu.Unlock()
+	if result := client.CallEndpoint(context.Background(), Request{}); result.Failed() {
+		t.Fatalf("result=%+v, want the call retried after a refresh", result)
+	}
+	mu.Lock()
+	if fmt.Sprint(grants) != "[password refresh_token]" {
+		t.Fatalf("grants=%v, want one password grant and one refresh", grants)
+	}
+	mu.Unlock()
+
+	denied, err := NewOAuth2(OAuth2Config{TokenURL: tokens.URL, ClientID: "loadgen", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = go_loadgen.Chain[Request, Result](base, denied.Middleware())
+	if result := client.CallEndpoint(context.Background(), Request{}); result.Class != ClassAuth || result.StatusCode != 0 {
+		t.Fatalf("result=%+v, want the call failed without being sent", result)
+	}
+	if _, err := NewOAuth2(OAuth2Config{TokenURL: tokens.URL, Username: "alice"}); err == nil {
+		t.Fatal("expected a username without a password to be rejected")
+	}
+}
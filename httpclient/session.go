@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// Session is the state of one virtual user: a cookie jar and variables
+// captured from its responses with Config.Capture. It is safe for concurrent
+// use.
+type Session struct {
+	// Jar holds the user's cookies. A Client sends and stores cookies through
+	// it for calls in the session.
+	Jar http.CookieJar
+
+	mu   sync.Mutex
+	vars Vars
+}
+
+// NewSession creates an empty session.
+func NewSession() *Session {
+	// cookiejar.New only fails for a broken public suffix list, and none is
+	// given.
+	jar, _ := cookiejar.New(nil)
+	return &Session{Jar: jar, vars: Vars{}}
+}
+
+// Var returns a captured variable.
+func (s *Session) Var(name string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.vars[name]
+	return value, ok
+}
+
+// SetVar stores a variable.
+func (s *Session) SetVar(name string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vars[name] = value
+}
+
+// Vars returns a copy of the session's variables.
+func (s *Session) Vars() Vars {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.vars)
+}
+
+func (s *Session) merge(vars Vars) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.Copy(s.vars, vars)
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context whose calls run in session.
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session a call runs in, so custom clients
+// can branch on it, for example to log in once per user.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok
+}
+
+// Sessions keeps one Session per virtual user, as numbered by
+// go_loadgen.WorkerFromContext, so each user of a closed phase keeps its
+// cookies and captured variables across its requests. It is safe for
+// concurrent use.
+type Sessions struct {
+	mu       sync.Mutex
+	sessions map[int]*Session
+}
+
+// NewSessions creates an empty set of sessions.
+func NewSessions() *Sessions {
+	return &Sessions{sessions: make(map[int]*Session)}
+}
+
+// Get returns the session of worker, creating it on first use.
+func (s *Sessions) Get(worker int) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[worker]
+	if !ok {
+		session = NewSession()
+		s.sessions[worker] = session
+	}
+	return session
+}
+
+// Reset discards every session, so users start over, for example between
+// phases.
+func (s *Sessions) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// SessionMiddleware runs each call in the session of the virtual user or
+// worker that issued it. Calls without a worker run without a session. It
+// wraps a Client or a TemplateClient, whose templates then see the session's
+// variables.
+func SessionMiddleware[C any, R any](sessions *Sessions) go_loadgen.ClientMiddleware[C, R] {
+	return func(next go_loadgen.Client[C, R]) go_loadgen.Client[C, R] {
+		return go_loadgen.ClientFunc[C, R](func(ctx context.Context, request C) R {
+			if worker, ok := go_loadgen.WorkerFromContext(ctx); ok {
+				ctx = WithSession(ctx, sessions.Get(worker))
+			}
+			return next.CallEndpoint(ctx, request)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+func TestSessionsKeepStatePerUser(t *testing.T) {
+	var mu sync.Mutex
+	logins, acts := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: fmt.Sprint(logins)})
+			fmt.Fprintf(w, `{"token":"t%d"}`, logins)
+		case "/act":
+			cookie, err := r.Cookie("sid")
+			if err != nil || r.Header.Get("X-Token") != "t"+cookie.Value {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			acts++
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(Config{URL: server.URL, Capture: func(_ *http.Response, body []byte) Vars {
+		var login struct{ Token string }
+		if json.Unmarshal(body, &login) != nil || login.Token == "" {
+			return nil
+		}
+		return Vars{"token": login.Token}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := NewTemplateClient(client, Request{URL: "/act", Header: http.Header{"X-Token": {"{{.Session.token}}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each user logs in once, then acts with its cookie and captured token.
+	flow := go_loadgen.ClientFunc[Vars, Result](func(ctx context.Context, vars Vars) Result {
+		session, ok := SessionFromContext(ctx)
+		if !ok {
+			t.Error("call has no session")
+		}
+		if _, ok := session.Var("token"); !ok {
+			return client.CallEndpoint(ctx, Request{URL: "/login"})
+		}
+		return act.CallEndpoint(ctx, vars)
+	})
+	sessions := NewSessions()
+	endpoint, err := go_loadgen.NewEndpoint[Vars, Result](go_loadgen.Chain[Vars, Result](flow, SessionMiddleware[Vars, Result](sessions)), varsProvider{}, &resultCollector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload, err := go_loadgen.NewWorkload(go_loadgen.Spec{
+		Duration:  50 * time.Millisecond,
+		Endpoints: map[string]go_loadgen.Endpoint{"flow": endpoint},
+		Phases:    []go_loadgen.Phase{{Duration: 50 * time.Millisecond, RPS: 400, Users: 2, Targets: []go_loadgen.Target{{Endpoint: "flow", Weight: 1}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := workload.Run(context.Background())
+	if err != nil || report.Failed != 0 {
+		t.Fatalf("report=%+v err=%v, want every call authorized", report, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if logins != 2 || acts == 0 {
+		t.Fatalf("logins=%d acts=%d, want one login per user", logins, acts)
+	}
+	if token, _ := sessions.Get(0).Var("token"); token == nil || token == sessions.Get(1).Vars()["token"] {
+		t.Fatalf("token=%v, want a distinct token per user", token)
+	}
+}
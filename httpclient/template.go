@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// Vars are the variables a data provider supplies for one templated request.
+type Vars map[string]any
+
+// TemplateData is what request templates are executed with. Templates refer
+// to its fields, as in "/users/{{.Vars.id}}/orders",
+// "Bearer {{.Session.token}}", or "{{.Phase.Name}}-{{.Worker}}".
+type TemplateData struct {
+	Vars Vars
+	// Session holds the variables of the call's Session, or is empty for
+	// calls without one.
+	Session Vars
+	// Phase is the phase that issued the request, if any.
+	Phase go_loadgen.PhaseInfo
+	// Worker is the virtual user or pool worker that issued the request, or
+	// -1 for requests without one.
+	Worker int
+	// Scheduled is when the workload scheduled the request, or the time of
+	// rendering outside a workload.
+	Scheduled time.Time
+}
+
+// TemplateClient implements go_loadgen.Client[Vars, Result]. It renders a
+// request template with each call's variables and sends the result through a
+// Client, so parameterized endpoints need only a data provider of Vars.
+type TemplateClient struct {
+	client *Client
+	method string
+	url    *template.Template
+	header map[string][]*template.Template
+	body   *template.Template
+}
+
+// NewTemplateClient parses the URL, header values, and body of request as
+// text/template templates sent through client. The method is used as is.
+func NewTemplateClient(client *Client, request Request) (*TemplateClient, error) {
+	if client == nil {
+		return nil, errors.New("client must be non-nil")
+	}
+	c := &TemplateClient{client: client, method: request.Method, header: make(map[string][]*template.Template, len(request.Header))}
+	var err error
+	if c.url, err = parse("url", request.URL); err != nil {
+		return nil, err
+	}
+	for key, values := range request.Header {
+		for _, value := range values {
+			parsed, err := parse("header "+key, value)
+			if err != nil {
+				return nil, err
+			}
+			c.header[key] = append(c.header[key], parsed)
+		}
+	}
+	if request.Body != nil {
+		if c.body, err = parse("body", string(request.Body)); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// CallEndpoint implements go_loadgen.Client. Calls whose template fails to
+// render are not sent and fail with ClassOther.
+func (c *TemplateClient) CallEndpoint(ctx context.Context, vars Vars) Result {
+	request, err := c.Render(ctx, vars)
+	if err != nil {
+		return Result{Method: c.method, URL: c.url.Root.String()}.fail(ClassOther, err)
+	}
+	return c.client.CallEndpoint(ctx, request)
+}
+
+// Render executes the template for one call without sending it.
+func (c *TemplateClient) Render(ctx context.Context, vars Vars) (Request, error) {
+	data := TemplateData{Vars: vars, Worker: -1, Scheduled: time.Now()}
+	data.Phase, _ = go_loadgen.PhaseFromContext(ctx)
+	if session, ok := SessionFromContext(ctx); ok {
+		data.Session = session.Vars()
+	}
+	if worker, ok := go_loadgen.WorkerFromContext(ctx); ok {
+		data.Worker = worker
+	}
+	if scheduled, ok := go_loadgen.ScheduledAt(ctx); ok {
+		data.Scheduled = scheduled
+	}
+	request := Request{Method: c.method}
+	url, err := execute(c.url, data)
+	if err != nil {
+		return Request{}, err
+	}
+	request.URL = string(url)
+	if len(c.header) != 0 {
+		request.Header = make(http.Header, len(c.header))
+		for key, values := range c.header {
+			for _, value := range values {
+				rendered, err := execute(value, data)
+				if err != nil {
+					return Request{}, err
+				}
+				request.Header[key] = append(request.Header[key], string(rendered))
+			}
+		}
+	}
+	if c.body != nil {
+		if request.Body, err = execute(c.body, data); err != nil {
+			return Request{}, err
+		}
+	}
+	return request, nil
+}
+
+// parse parses one template. Missing variables are errors rather than
+// "<no value>", so a provider that omits one fails loudly.
+func parse(name, text string) (*template.Template, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s template: %w", name, err)
+	}
+	return parsed, nil
+}
+
+func execute(t *template.Template, data TemplateData) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+func TestTemplateClientRendersRequests(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Phase")+" "+string(body))
+	}))
+	defer server.Close()
+	client, err := New(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	templated, err := NewTemplateClient(client, Request{
+		Method: http.MethodPost,
+		URL:    "/users/{{.Vars.id}}/orders",
+		Header: http.Header{"X-Phase": {"{{.Phase.Name}}"}},
+		Body:   []byte(`{"item":{{printf "%q" .Vars.item}}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := go_loadgen.NewEndpoint[Vars, Result](templated, varsProvider{}, &resultCollector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload, err := go_loadgen.NewWorkload(go_loadgen.Spec{
+		Duration:  30 * time.Millisecond,
+		Endpoints: map[string]go_loadgen.Endpoint{"orders": endpoint},
+		Phases:    []go_loadgen.Phase{{Name: "ramp", Duration: 30 * time.Millisecond, RPS: 100, Targets: []go_loadgen.Target{{Endpoint: "orders", Weight: 1}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err := workload.Run(context.Background()); err != nil || report.Completed == 0 || report.Failed != 0 {
+		t.Fatalf("report=%+v err=%v, want successful templated calls", report, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := `POST /users/42/orders ramp {"item":"book"}`; seen[0] != want {
+		t.Fatalf("request=%q, want %q", seen[0], want)
+	}
+
+	result := templated.CallEndpoint(context.Background(), Vars{"item": "book"})
+	if result.Class != ClassOther || !strings.Contains(result.Error, "id") {
+		t.Fatalf("result=%+v, want a missing variable to fail the call", result)
+	}
+	if _, err := NewTemplateClient(client, Request{URL: "/users/{{.Vars.id"}); err == nil {
+		t.Fatal("expected a malformed template to be rejected")
+	}
+}
+
+type varsProvider struct{}
+
+func (varsProvider) GetData() Vars { return Vars{"id": 42, "item": "book"} }
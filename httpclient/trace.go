@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// connTrace records how a request obtained its connection. Transport
+// goroutines call its hooks, possibly after the request has moved on, so its
+// fields are guarded.
+type connTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tlsHandshake time.Duration
+	reused       bool
+}
+
+func (t *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsHandshake = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+	}
+}
+
+// record copies the connection timings into result. A reused connection was
+// not established for the request, even if a dial started for it finished.
+func (t *connTrace) record(result *Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result.Reused = t.reused
+	if !t.reused {
+		result.DNS, result.Connect, result.TLSHandshake = t.dns, t.connect, t.tlsHandshake
+	}
+}
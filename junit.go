@@ -0,0 +1,61 @@
+package go_loadgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Output    string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a run's threshold results as a JUnit XML test suite
+// named suite, with one test case per threshold, so CI servers such as
+// Jenkins and GitLab show load-test pass/fail natively.
+func WriteJUnit(w io.Writer, suite string, report Report) error {
+	s := junitSuite{Name: suite, Tests: len(report.Thresholds), Time: report.Duration.Seconds()}
+	for i, result := range report.Thresholds {
+		observed := fmt.Sprintf("p%s %s, error rate %.4f, throughput %.1f rps", strconv.FormatFloat(result.percentile(), 'f', -1, 64), result.Latency, result.ErrorRate, result.Throughput)
+		c := junitCase{
+			Name:      fmt.Sprintf("threshold %d: %s", i, result.Threshold),
+			ClassName: suite,
+			Time:      report.Duration.Seconds(),
+			Output:    observed,
+		}
+		if !result.Passed {
+			s.Failures++
+			c.Failure = &junitFailure{Message: "threshold not met: " + observed, Text: fmt.Sprintf("want %s, got %s", result.Threshold, observed)}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,44 @@
+package go_loadgen
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnitReportsThresholdsAsTestCases(t *testing.T) {
+	report := Report{
+		Duration: 2 * time.Second,
+		Thresholds: []ThresholdResult{
+			{Threshold: Threshold{MaxLatency: 200 * time.Millisecond}, Latency: 150 * time.Millisecond, Passed: true},
+			{Threshold: Threshold{MaxErrorRate: 0.01}, ErrorRate: 0.05},
+		},
+	}
+	var out strings.Builder
+	if err := WriteJUnit(&out, "checkout", report); err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Cases    []struct {
+			Name    string `xml:"name,attr"`
+			Failure *struct {
+				Message string `xml:"message,attr"`
+			} `xml:"failure"`
+		} `xml:"testcase"`
+	}
+	if err := xml.Unmarshal([]byte(out.String()), &suite); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, out.String())
+	}
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.Cases) != 2 {
+		t.Fatalf("suite=%+v", suite)
+	}
+	if suite.Cases[0].Name != "threshold 0: p99 <= 200ms" || suite.Cases[0].Failure != nil {
+		t.Fatalf("first case=%+v, want a passing latency check", suite.Cases[0])
+	}
+	if failure := suite.Cases[1].Failure; failure == nil || !strings.Contains(failure.Message, "error rate 0.0500") {
+		t.Fatalf("second case=%+v, want a failure with the observed error rate", suite.Cases[1])
+	}
+}
@@ -40,7 +40,7 @@ func TestLocalHTTPHighLoad(t *testing.T) {
 		Phases: []Phase{{Duration: duration, RPS: rps, Targets: []Target{{Endpoint: "http", Weight: 1}}}},
 	})
 
-	report := workload.Run(context.Background())
+	report := mustRun(t, workload)
 	if report.Scheduled != rps*uint64(duration/time.Second) || report.Issued+report.Missed != report.Scheduled || report.Completed != report.Issued {
 		t.Fatalf("scheduled=%d issued=%d missed=%d completed=%d", report.Scheduled, report.Issued, report.Missed, report.Completed)
 	}
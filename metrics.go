@@ -0,0 +1,116 @@
+package go_loadgen
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the latency histogram bucket bounds used by metric
+// exporters given none, matching the Prometheus client defaults.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// phaseMetrics holds one phase's cumulative counters and latency histogram.
+type phaseMetrics struct {
+	info     PhaseInfo
+	requests atomic.Uint64
+	failures atomic.Uint64
+	timeouts atomic.Uint64
+	// nanos is the latency sum in nanoseconds.
+	nanos atomic.Uint64
+	// buckets counts latencies per bucket bound; latencies above the last
+	// bound are only counted in requests.
+	buckets []atomic.Uint64
+}
+
+// metricSet aggregates samples per phase for metric exporters. Phases are
+// keyed by workload too, so workloads sharing an exporter, such as in a Group,
+// keep separate series.
+type metricSet struct {
+	buckets []time.Duration
+	mu      sync.RWMutex
+	phases  map[phaseKeyOf]*phaseMetrics
+}
+
+// phaseKeyOf identifies a phase across workloads.
+type phaseKeyOf struct {
+	workload string
+	index    int
+}
+
+func newMetricSet(buckets []time.Duration) (*metricSet, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	for i, bound := range buckets {
+		if bound <= 0 || (i > 0 && bound <= buckets[i-1]) {
+			return nil, errors.New("latency buckets must be positive and strictly ascending")
+		}
+	}
+	return &metricSet{buckets: slices.Clone(buckets), phases: make(map[phaseKeyOf]*phaseMetrics)}, nil
+}
+
+func (s *metricSet) observe(sample Sample) {
+	phase := s.phase(sample.Phase)
+	phase.requests.Add(1)
+	if sample.Failed {
+		phase.failures.Add(1)
+	}
+	if sample.TimedOut {
+		phase.timeouts.Add(1)
+	}
+	latency := max(sample.Latency, 0)
+	phase.nanos.Add(uint64(latency))
+	if i, _ := slices.BinarySearch(s.buckets, latency); i < len(s.buckets) {
+		phase.buckets[i].Add(1)
+	}
+}
+
+func (s *metricSet) phase(info PhaseInfo) *phaseMetrics {
+	key := phaseKeyOf{workload: info.Workload, index: info.Index}
+	s.mu.RLock()
+	phase, ok := s.phases[key]
+	s.mu.RUnlock()
+	if ok {
+		return phase
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if phase, ok = s.phases[key]; !ok {
+		phase = &phaseMetrics{info: info, buckets: make([]atomic.Uint64, len(s.buckets))}
+		s.phases[key] = phase
+	}
+	return phase
+}
+
+// snapshot returns the phases observed so far ordered by workload and index.
+func (s *metricSet) snapshot() []*phaseMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	phases := slices.Collect(maps.Values(s.phases))
+	slices.SortFunc(phases, func(a, b *phaseMetrics) int {
+		return cmp.Or(cmp.Compare(a.info.Workload, b.info.Workload), cmp.Compare(a.info.Index, b.info.Index))
+	})
+	return phases
+}
+
+// histogram returns the phase's request count and per-bucket counts, with the
+// count read first so the buckets never exceed it mid-run.
+func (p *phaseMetrics) histogram() (uint64, []uint64) {
+	count := p.requests.Load()
+	buckets := make([]uint64, len(p.buckets))
+	var total uint64
+	for i := range p.buckets {
+		buckets[i] = min(p.buckets[i].Load(), count-total)
+		total += buckets[i]
+	}
+	return count, buckets
+}
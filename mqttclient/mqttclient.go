@@ -0,0 +1,364 @@
+/*
+Package mqttclient is a go_loadgen client that publishes to an MQTT broker
+from a fleet of simulated devices, for load testing IoT backends.
+
+Each device keeps one persistent MQTT 3.1.1 connection under its own client
+ID and publishes one message at a time, as a real device would. Requests are
+spread over devices by the virtual user or worker that issued them, so closed
+phases with Users map each user to a device. QoS 0 and 1 are supported.
+*/
+package mqttclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// Packet types of MQTT 3.1.1, in the high nibble of the first byte.
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPuback     = 0x40
+	packetDisconnect = 0xe0
+)
+
+// Config describes the broker and the device fleet.
+type Config struct {
+	// Address is the broker's host and port, such as "localhost:1883".
+	Address string
+	// Devices is the number of simulated devices, each with one connection.
+	// Zero means one.
+	Devices int
+	// ClientIDPrefix names devices: device i connects as the prefix followed
+	// by i, so repeated runs reuse the same client IDs.
+	ClientIDPrefix string
+	// QoS is the publish quality of service, 0 or 1. With 1, a publish
+	// completes when the broker acknowledges it.
+	QoS byte
+	// PersistentSession asks the broker to keep device sessions between
+	// connections instead of starting clean ones.
+	PersistentSession bool
+	Username          string
+	Password          string
+	// Timeout bounds connecting and each publish, in addition to any
+	// deadline on the request context. Zero relies on the context alone.
+	Timeout time.Duration
+	// TLS connects to the broker over TLS, usually on port 8883, for example
+	// built with the tlsconfig package. Nil connects in plaintext.
+	TLS *tls.Config
+}
+
+// Request is one message to publish.
+type Request struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Result is the measurement of one publish. Latency spans from the call,
+// including any wait for the device's previous publish and any reconnect,
+// until the message is written for QoS 0 or acknowledged for QoS 1. Connect
+// is the part spent connecting, or zero when the connection was reused.
+type Result struct {
+	ClientID string
+	Connect  time.Duration
+	Latency  time.Duration
+	// TimedOut reports calls that hit a deadline.
+	TimedOut bool
+	Error    string
+}
+
+// Failed implements go_loadgen.Outcome.
+func (r Result) Failed() bool { return r.Error != "" }
+
+// CSVHeaders implements go_loadgen.CSVSerializable.
+func (r Result) CSVHeaders() []string {
+	return []string{"client_id", "connect_ms", "latency_ms", "timed_out", "error"}
+}
+
+// CSVRecord implements go_loadgen.CSVSerializable.
+func (r Result) CSVRecord() []string {
+	return []string{
+		r.ClientID,
+		strconv.FormatFloat(float64(r.Connect)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.FormatBool(r.TimedOut),
+		r.Error,
+	}
+}
+
+// Client implements go_loadgen.Client[Request, Result]. Close it after the
+// run to disconnect the devices.
+type Client struct {
+	config  Config
+	devices []device
+	next    atomic.Uint64
+	dialer  net.Dialer
+}
+
+type device struct {
+	mu       sync.Mutex
+	clientID string
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+}
+
+// New creates a client from config. Devices connect on their first publish.
+func New(config Config) (*Client, error) {
+	if config.Address == "" {
+		return nil, errors.New("broker address must be set")
+	}
+	if config.Devices < 0 || config.Timeout < 0 {
+		return nil, errors.New("devices and timeout cannot be negative")
+	}
+	if config.QoS > 1 {
+		return nil, fmt.Errorf("unsupported QoS %d", config.QoS)
+	}
+	c := &Client{config: config, devices: make([]device, max(config.Devices, 1))}
+	for i := range c.devices {
+		c.devices[i].clientID = config.ClientIDPrefix + strconv.Itoa(i)
+	}
+	return c, nil
+}
+
+// CallEndpoint implements go_loadgen.Client.
+func (c *Client) CallEndpoint(ctx context.Context, request Request) Result {
+	started := time.Now()
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+	device := c.device(ctx)
+	device.mu.Lock()
+	defer device.mu.Unlock()
+	result := Result{ClientID: device.clientID}
+	err := c.publish(ctx, device, request, &result)
+	result.Latency = time.Since(started)
+	if err != nil {
+		// The connection's state is unknown after an error; reconnect next time.
+		device.close(false)
+		var netErr net.Error
+		result.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded) || (ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout())
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Close disconnects every device.
+func (c *Client) Close() {
+	for i := range c.devices {
+		device := &c.devices[i]
+		device.mu.Lock()
+		device.close(true)
+		device.mu.Unlock()
+	}
+}
+
+// device picks the device for a request: the issuing worker's, or the next in
+// turn for requests without one.
+func (c *Client) device(ctx context.Context) *device {
+	if worker, ok := go_loadgen.WorkerFromContext(ctx); ok {
+		return &c.devices[worker%len(c.devices)]
+	}
+	return &c.devices[(c.next.Add(1)-1)%uint64(len(c.devices))]
+}
+
+// publish sends one message on the device's connection, connecting first if
+// needed. device.mu must be held.
+func (c *Client) publish(ctx context.Context, device *device, request Request, result *Result) error {
+	if device.conn == nil {
+		connected := time.Now()
+		err := c.connect(ctx, device)
+		result.Connect = time.Since(connected)
+		if err != nil {
+			return err
+		}
+	}
+	conn := device.conn
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// Cancellation interrupts blocked reads and writes.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer func() {
+		// A cancellation racing with the publish may have expired the
+		// deadline, so the connection is not reused.
+		if !stop() {
+			device.close(false)
+		}
+	}()
+
+	var header [2]byte
+	binary.BigEndian.PutUint16(header[:], uint16(len(request.Topic)))
+	body := append(header[:], request.Topic...)
+	var id uint16
+	if c.config.QoS == 1 {
+		device.packetID++
+		if device.packetID == 0 {
+			device.packetID = 1
+		}
+		id = device.packetID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, request.Payload...)
+	flags := c.config.QoS << 1
+	if request.Retain {
+		flags |= 1
+	}
+	if _, err := conn.Write(packet(packetPublish|flags, body)); err != nil {
+		return err
+	}
+	if c.config.QoS == 0 {
+		return nil
+	}
+	for {
+		kind, body, err := readPacket(device.reader)
+		if err != nil {
+			return err
+		}
+		if kind&0xf0 == packetPuback && len(body) == 2 && binary.BigEndian.Uint16(body) == id {
+			return nil
+		}
+	}
+}
+
+// connect opens the device's connection and completes the MQTT handshake.
+func (c *Client) connect(ctx context.Context, device *device) error {
+	var conn net.Conn
+	var err error
+	if c.config.TLS != nil {
+		dialer := tls.Dialer{NetDialer: &c.dialer, Config: c.config.TLS}
+		conn, err = dialer.DialContext(ctx, "tcp", c.config.Address)
+	} else {
+		conn, err = c.dialer.DialContext(ctx, "tcp", c.config.Address)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	flags := byte(0)
+	if !c.config.PersistentSession {
+		flags |= 0x02
+	}
+	if c.config.Username != "" {
+		flags |= 0x80
+	}
+	if c.config.Password != "" {
+		flags |= 0x40
+	}
+	// Protocol name, level 4 for 3.1.1, flags, and a zero keep-alive, which
+	// tells the broker not to expect pings between publishes.
+	body := []byte{0, 4, 'M', 'Q', 'T', 'T', 4, flags, 0, 0}
+	body = appendString(body, device.clientID)
+	if c.config.Username != "" {
+		body = appendString(body, c.config.Username)
+	}
+	if c.config.Password != "" {
+		body = appendString(body, c.config.Password)
+	}
+	reader := bufio.NewReader(conn)
+	if _, err := conn.Write(packet(packetConnect, body)); err != nil {
+		conn.Close()
+		return err
+	}
+	kind, ack, err := readPacket(reader)
+	switch {
+	case err != nil:
+	case kind != packetConnack || len(ack) != 2:
+		err = fmt.Errorf("unexpected packet type %#x during connect", kind)
+	case ack[1] != 0:
+		err = fmt.Errorf("broker refused connection with code %d", ack[1])
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	device.conn, device.reader = conn, reader
+	return nil
+}
+
+// close drops the device's connection, first sending DISCONNECT when
+// graceful. device.mu must be held.
+func (d *device) close(graceful bool) {
+	if d.conn == nil {
+		return
+	}
+	if graceful {
+		d.conn.SetDeadline(time.Now().Add(time.Second))
+		d.conn.Write(packet(packetDisconnect, nil))
+	}
+	d.conn.Close()
+	d.conn, d.reader = nil, nil
+}
+
+// packet frames body with a fixed header.
+func packet(first byte, body []byte) []byte {
+	framed := make([]byte, 0, len(body)+5)
+	framed = append(framed, first)
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		framed = append(framed, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(framed, body...)
+}
+
+// readPacket reads one packet, returning its first byte and body.
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	first, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var length, shift int
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return first, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,130 @@
+package mqttclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// broker is a minimal MQTT broker recording connects and publishes.
+type broker struct {
+	listener net.Listener
+	mu       sync.Mutex
+	clients  []string
+	messages []string
+	// ack controls whether QoS 1 publishes are acknowledged.
+	ack bool
+}
+
+func newBroker(t *testing.T) *broker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{listener: listener, ack: true}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	kind, body, err := readPacket(reader)
+	if err != nil || kind != packetConnect {
+		return
+	}
+	idLength := int(binary.BigEndian.Uint16(body[10:]))
+	b.mu.Lock()
+	b.clients = append(b.clients, string(body[12:12+idLength]))
+	b.mu.Unlock()
+	conn.Write(packet(packetConnack, []byte{0, 0}))
+	for {
+		kind, body, err := readPacket(reader)
+		if err != nil || kind == packetDisconnect {
+			return
+		}
+		qos := kind >> 1 & 3
+		topicLength := int(binary.BigEndian.Uint16(body))
+		topic, rest := string(body[2:2+topicLength]), body[2+topicLength:]
+		var id []byte
+		if qos > 0 {
+			id, rest = rest[:2], rest[2:]
+		}
+		b.mu.Lock()
+		b.messages = append(b.messages, topic+"="+string(rest))
+		ack := b.ack
+		b.mu.Unlock()
+		if qos > 0 && ack {
+			conn.Write(packet(packetPuback, id))
+		}
+	}
+}
+
+func TestDevicesPublishOverPersistentConnections(t *testing.T) {
+	b := newBroker(t)
+	client, err := New(Config{Address: b.listener.Addr().String(), Devices: 3, ClientIDPrefix: "sensor-", QoS: 1, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 6 {
+		result := client.CallEndpoint(context.Background(), Request{Topic: "fleet/temp", Payload: []byte{'0' + byte(i)}})
+		if result.Failed() || result.Latency <= 0 {
+			t.Fatalf("publish %d: result=%+v", i, result)
+		}
+		if want := "sensor-" + string('0'+byte(i%3)); result.ClientID != want || (i < 3) != (result.Connect > 0) {
+			t.Fatalf("publish %d: result=%+v, want device %s connecting only on its first publish", i, result, want)
+		}
+	}
+	client.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !slices.Equal(b.clients, []string{"sensor-0", "sensor-1", "sensor-2"}) {
+		t.Fatalf("clients=%v, want one connection per device", b.clients)
+	}
+	if len(b.messages) != 6 || b.messages[5] != "fleet/temp=5" {
+		t.Fatalf("messages=%v", b.messages)
+	}
+}
+
+func TestUnacknowledgedPublishTimesOutAndReconnects(t *testing.T) {
+	b := newBroker(t)
+	b.ack = false
+	client, err := New(Config{Address: b.listener.Addr().String(), QoS: 1, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if result := client.CallEndpoint(context.Background(), Request{Topic: "t"}); !result.TimedOut || result.Error == "" {
+		t.Fatalf("result=%+v, want a timeout", result)
+	}
+	b.mu.Lock()
+	b.ack = true
+	b.mu.Unlock()
+	if result := client.CallEndpoint(context.Background(), Request{Topic: "t"}); result.Failed() || result.Connect == 0 {
+		t.Fatalf("result=%+v, want a successful publish on a new connection", result)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []Config{{}, {Address: "x", QoS: 2}, {Address: "x", Devices: -1}} {
+		if _, err := New(config); err == nil {
+			t.Fatalf("config=%+v, want it rejected", config)
+		}
+	}
+}
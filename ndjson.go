@@ -0,0 +1,172 @@
+package go_loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// NDJSONCollector writes each result as one line of a newline-delimited JSON
+// file, so results with nested structures need not be flattened into CSV
+// columns. A custom encoder can redact or reshape results on the way out.
+type NDJSONCollector[R any] struct {
+	encode func(R) ([]byte, error)
+	file   *os.File
+	writer *bufio.Writer
+	mu     sync.Mutex
+	errMu  sync.Mutex
+	err    error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewNDJSONCollector creates a collector that writes to filePath and flushes
+// every flushInterval. encode converts a result to one line and must not emit
+// newlines; nil uses json.Marshal. Results that fail to encode or encode to
+// more than one line are skipped and reported by Err.
+func NewNDJSONCollector[R any](filePath string, flushInterval time.Duration, encode func(R) ([]byte, error)) (*NDJSONCollector[R], error) {
+	if flushInterval <= 0 {
+		return nil, fmt.Errorf("flush interval must be positive")
+	}
+	if encode == nil {
+		encode = func(result R) ([]byte, error) { return json.Marshal(result) }
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &NDJSONCollector[R]{
+		encode: encode,
+		file:   file,
+		writer: bufio.NewWriterSize(file, 256*1024),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go c.runFlush(ctx, flushInterval)
+	return c, nil
+}
+
+// Collect writes a result.
+func (c *NDJSONCollector[R]) Collect(result R) {
+	line, ok := c.line(result)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.write(line)
+}
+
+// CollectBatch writes results under one lock.
+func (c *NDJSONCollector[R]) CollectBatch(results []R) {
+	lines := make([][]byte, 0, len(results))
+	for _, result := range results {
+		if line, ok := c.line(result); ok {
+			lines = append(lines, line)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, line := range lines {
+		c.write(line)
+	}
+}
+
+// line encodes a result, reporting results that cannot be written as one line.
+func (c *NDJSONCollector[R]) line(result R) ([]byte, bool) {
+	line, err := c.encode(result)
+	if err == nil && bytes.IndexByte(line, '\n') >= 0 {
+		err = errors.New("encoded result contains a newline")
+	}
+	if err != nil {
+		c.setErr(fmt.Errorf("encode result: %w", err))
+		return nil, false
+	}
+	return line, true
+}
+
+// write appends one line. c.mu must be held.
+func (c *NDJSONCollector[R]) write(line []byte) {
+	if c.file == nil {
+		c.setErr(errNDJSONCollectorClosed)
+		return
+	}
+	if _, err := c.writer.Write(line); err != nil {
+		c.setErr(fmt.Errorf("write NDJSON record: %w", err))
+		return
+	}
+	if err := c.writer.WriteByte('\n'); err != nil {
+		c.setErr(fmt.Errorf("write NDJSON record: %w", err))
+	}
+}
+
+var errNDJSONCollectorClosed = errors.New("NDJSON collector is closed")
+
+// Close flushes buffered lines and closes the file. Results collected
+// afterwards are dropped and reported by Err.
+func (c *NDJSONCollector[R]) Close() {
+	c.cancel()
+	<-c.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return
+	}
+	if err := c.writer.Flush(); err != nil {
+		c.setErr(fmt.Errorf("flush NDJSON file: %w", err))
+	}
+	if err := c.file.Close(); err != nil {
+		c.setErr(fmt.Errorf("close NDJSON file: %w", err))
+	}
+	c.file = nil
+}
+
+// CloseAndErr closes the collector and returns the first encode, write,
+// flush, or close error observed by the collector.
+func (c *NDJSONCollector[R]) CloseAndErr() error {
+	c.Close()
+	return c.Err()
+}
+
+// Err returns the first encode, write, flush, or close error observed by the
+// collector.
+func (c *NDJSONCollector[R]) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	return c.err
+}
+
+func (c *NDJSONCollector[R]) setErr(err error) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *NDJSONCollector[R]) runFlush(ctx context.Context, interval time.Duration) {
+	defer close(c.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			c.mu.Lock()
+			if c.file != nil {
+				if err := c.writer.Flush(); err != nil {
+					c.setErr(fmt.Errorf("flush NDJSON file: %w", err))
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package go_loadgen
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ndjsonTestResult struct {
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers"`
+	Token    string            `json:"token,omitempty"`
+}
+
+func TestNDJSONCollectorWritesOneLinePerResult(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "results.ndjson")
+	collector, err := NewNDJSONCollector[ndjsonTestResult](filename, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Failed to create NDJSON collector: %v", err)
+	}
+	collector.Collect(ndjsonTestResult{Endpoint: "api", Headers: map[string]string{"status": "200"}})
+	collector.CollectBatch([]ndjsonTestResult{{Endpoint: "a"}, {Endpoint: "b"}})
+	if err := collector.CloseAndErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read NDJSON file: %v", err)
+	}
+	want := `{"endpoint":"api","headers":{"status":"200"}}` + "\n" +
+		`{"endpoint":"a","headers":null}` + "\n" +
+		`{"endpoint":"b","headers":null}` + "\n"
+	if string(content) != want {
+		t.Fatalf("content=%q, want %q", content, want)
+	}
+}
+
+func TestNDJSONCollectorUsesCustomEncoder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "results.ndjson")
+	encode := func(result ndjsonTestResult) ([]byte, error) {
+		if result.Endpoint == "" {
+			return nil, errors.New("missing endpoint")
+		}
+		return []byte(result.Endpoint + " token=" + strings.Repeat("*", len(result.Token))), nil
+	}
+	collector, err := NewNDJSONCollector(filename, time.Second, encode)
+	if err != nil {
+		t.Fatalf("Failed to create NDJSON collector: %v", err)
+	}
+	collector.Collect(ndjsonTestResult{Endpoint: "api", Token: "secret"})
+	collector.Collect(ndjsonTestResult{})
+	err = collector.CloseAndErr()
+	if err == nil || !strings.Contains(err.Error(), "missing endpoint") {
+		t.Fatalf("err=%v, want the encode error", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read NDJSON file: %v", err)
+	}
+	if want := "api token=******\n"; string(content) != want {
+		t.Fatalf("content=%q, want %q", content, want)
+	}
+}
+
+func TestNDJSONCollectorRejectsMultilineRecords(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "results.ndjson")
+	encode := func(result ndjsonTestResult) ([]byte, error) { return json.MarshalIndent(result, "", "  ") }
+	collector, err := NewNDJSONCollector(filename, time.Second, encode)
+	if err != nil {
+		t.Fatalf("Failed to create NDJSON collector: %v", err)
+	}
+	collector.Collect(ndjsonTestResult{Endpoint: "api"})
+	if err := collector.CloseAndErr(); err == nil || !strings.Contains(err.Error(), "newline") {
+		t.Fatalf("err=%v, want the multiline record rejected", err)
+	}
+	if content, _ := os.ReadFile(filename); len(content) != 0 {
+		t.Fatalf("content=%q, want nothing written", content)
+	}
+}
+
+func TestNDJSONCollectorReportsCollectAfterClose(t *testing.T) {
+	collector, err := NewNDJSONCollector[ndjsonTestResult](filepath.Join(t.TempDir(), "results.ndjson"), time.Second, nil)
+	if err != nil {
+		t.Fatalf("Failed to create NDJSON collector: %v", err)
+	}
+	collector.Close()
+	collector.Collect(ndjsonTestResult{Endpoint: "late"})
+	if err := collector.Err(); !errors.Is(err, errNDJSONCollectorClosed) {
+		t.Fatalf("err=%v, want %v", err, errNDJSONCollectorClosed)
+	}
+}
+
+func TestNewNDJSONCollector_InvalidFlushInterval(t *testing.T) {
+	if _, err := NewNDJSONCollector[ndjsonTestResult](filepath.Join(t.TempDir(), "x.ndjson"), 0, nil); err == nil {
+		t.Fatal("expected an error for a zero flush interval")
+	}
+}
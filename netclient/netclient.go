@@ -0,0 +1,210 @@
+/*
+Package netclient is a go_loadgen client for raw TCP and UDP services, such as
+custom binary protocols and syslog receivers.
+
+Each call dials the target, writes the request's payload, and optionally waits
+for a reply, measuring the exchange into a Result. Result implements
+go_loadgen.Outcome and go_loadgen.CSVSerializable.
+*/
+package netclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	go_loadgen "github.com/luccadibe/go-loadgen"
+)
+
+// defaultMaxResponse is the reply size read when Config.MaxResponse is zero.
+const defaultMaxResponse = 64 * 1024
+
+// Config describes the target and the exchange.
+type Config struct {
+	// Network is "tcp", "udp", or one of their address-family variants.
+	Network string
+	// Address is the target's host and port.
+	Address string
+	// AwaitResponse waits for a reply after sending. Without it, a call
+	// completes once the payload is written.
+	AwaitResponse bool
+	// Delimiter ends a TCP reply, such as "\n" for line protocols. Without
+	// one, the first read of a reply completes it. A UDP reply is always one
+	// datagram.
+	Delimiter []byte
+	// MaxResponse bounds the reply read, 64 KiB when zero.
+	MaxResponse int
+	// KeepResponse stores replies in Result.Response for validation.
+	KeepResponse bool
+	// Timeout bounds each whole exchange, in addition to any deadline on the
+	// request context. Zero relies on the context alone.
+	Timeout time.Duration
+	// FromSchedule measures latency from the request's scheduled send time,
+	// as reported by go_loadgen.ScheduledAt, instead of the start of dialing.
+	FromSchedule bool
+	// TLS wraps TCP connections in TLS, for example built with the tlsconfig
+	// package. Nil sends plaintext. UDP does not support it.
+	TLS *tls.Config
+}
+
+// Request is one payload to send.
+type Request struct {
+	Payload []byte
+}
+
+// Result is the measurement of one exchange. Latency spans from dialing, or
+// from the schedule with Config.FromSchedule, until the payload is written or
+// the reply is read; Connect is the dialing part of it, including any TLS
+// handshake.
+type Result struct {
+	Connect       time.Duration
+	Latency       time.Duration
+	BytesSent     int
+	BytesReceived int
+	Response      []byte
+	// TimedOut reports calls that hit a deadline.
+	TimedOut bool
+	Error    string
+}
+
+// Failed implements go_loadgen.Outcome.
+func (r Result) Failed() bool { return r.Error != "" }
+
+// CSVHeaders implements go_loadgen.CSVSerializable.
+func (r Result) CSVHeaders() []string {
+	return []string{"connect_ms", "latency_ms", "bytes_sent", "bytes_received", "timed_out", "error"}
+}
+
+// CSVRecord implements go_loadgen.CSVSerializable.
+func (r Result) CSVRecord() []string {
+	return []string{
+		strconv.FormatFloat(float64(r.Connect)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.Itoa(r.BytesSent),
+		strconv.Itoa(r.BytesReceived),
+		strconv.FormatBool(r.TimedOut),
+		r.Error,
+	}
+}
+
+// Client implements go_loadgen.Client[Request, Result].
+type Client struct {
+	config Config
+	dialer net.Dialer
+}
+
+// New creates a client from config.
+func New(config Config) (*Client, error) {
+	switch config.Network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("unsupported network %q", config.Network)
+	}
+	if config.Address == "" {
+		return nil, errors.New("address must be set")
+	}
+	if config.MaxResponse < 0 || config.Timeout < 0 {
+		return nil, errors.New("max response and timeout cannot be negative")
+	}
+	if config.MaxResponse == 0 {
+		config.MaxResponse = defaultMaxResponse
+	}
+	c := &Client{config: config}
+	if c.isUDP() && config.TLS != nil {
+		return nil, errors.New("TLS is not supported over UDP")
+	}
+	return c, nil
+}
+
+// CallEndpoint implements go_loadgen.Client.
+func (c *Client) CallEndpoint(ctx context.Context, request Request) Result {
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	if scheduled, ok := go_loadgen.ScheduledAt(ctx); ok && c.config.FromSchedule {
+		started = scheduled
+	}
+	var result Result
+	dialed := time.Now()
+	conn, err := c.dial(ctx)
+	result.Connect = time.Since(dialed)
+	if err != nil {
+		return result.fail(ctx, started, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Cancellation interrupts blocked reads and writes.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	result.BytesSent, err = conn.Write(request.Payload)
+	if err != nil {
+		return result.fail(ctx, started, err)
+	}
+	if c.config.AwaitResponse {
+		response, err := c.read(conn)
+		result.BytesReceived = len(response)
+		if c.config.KeepResponse {
+			result.Response = response
+		}
+		if err != nil {
+			return result.fail(ctx, started, err)
+		}
+	}
+	result.Latency = time.Since(started)
+	return result
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.config.TLS != nil {
+		dialer := tls.Dialer{NetDialer: &c.dialer, Config: c.config.TLS}
+		return dialer.DialContext(ctx, c.config.Network, c.config.Address)
+	}
+	return c.dialer.DialContext(ctx, c.config.Network, c.config.Address)
+}
+
+// read reads one reply.
+func (c *Client) read(conn net.Conn) ([]byte, error) {
+	buffer := make([]byte, c.config.MaxResponse)
+	if len(c.config.Delimiter) == 0 || c.isUDP() {
+		n, err := conn.Read(buffer)
+		return buffer[:n], err
+	}
+	var n int
+	for {
+		read, err := conn.Read(buffer[n:])
+		n += read
+		if bytes.Contains(buffer[max(0, n-read-len(c.config.Delimiter)+1):n], c.config.Delimiter) {
+			return buffer[:n], nil
+		}
+		if err != nil {
+			return buffer[:n], err
+		}
+		if n == len(buffer) {
+			return buffer[:n], fmt.Errorf("reply exceeds %d bytes without a delimiter", len(buffer))
+		}
+	}
+}
+
+func (c *Client) isUDP() bool { return c.config.Network[:3] == "udp" }
+
+func (r Result) fail(ctx context.Context, started time.Time, err error) Result {
+	r.Latency = time.Since(started)
+	var netErr net.Error
+	r.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil)
+	if ctx.Err() != nil {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	r.Error = err.Error()
+	return r
+}
@@ -0,0 +1,120 @@
+package netclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineServer replies to each line with its upper-case form, in two writes so
+// clients must read up to the delimiter.
+func lineServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil || line == "silent\n" {
+					time.Sleep(time.Second)
+					return
+				}
+				reply := strings.ToUpper(line)
+				conn.Write([]byte(reply[:2]))
+				time.Sleep(5 * time.Millisecond)
+				conn.Write([]byte(reply[2:]))
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTCPClientReadsUntilDelimiter(t *testing.T) {
+	client, err := New(Config{Network: "tcp", Address: lineServer(t), AwaitResponse: true, Delimiter: []byte("\n"), KeepResponse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := client.CallEndpoint(context.Background(), Request{Payload: []byte("hello\n")})
+	if result.Failed() || string(result.Response) != "HELLO\n" || result.BytesSent != 6 || result.BytesReceived != 6 {
+		t.Fatalf("result=%+v, want the whole reply", result)
+	}
+	if result.Connect <= 0 || result.Latency < result.Connect {
+		t.Fatalf("result=%+v, want connect time within the latency", result)
+	}
+	if record := result.CSVRecord(); len(record) != len(result.CSVHeaders()) || record[4] != "false" {
+		t.Fatalf("record=%v", record)
+	}
+}
+
+func TestTCPClientTimesOutAndSendsWithoutWaiting(t *testing.T) {
+	address := lineServer(t)
+	client, err := New(Config{Network: "tcp", Address: address, AwaitResponse: true, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := client.CallEndpoint(context.Background(), Request{Payload: []byte("silent\n")}); !result.TimedOut || !result.Failed() {
+		t.Fatalf("result=%+v, want a timeout", result)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if result := client.CallEndpoint(ctx, Request{Payload: []byte("silent\n")}); result.TimedOut || !strings.Contains(result.Error, "canceled") {
+		t.Fatalf("result=%+v, want a cancellation", result)
+	}
+
+	sender, err := New(Config{Network: "tcp", Address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := sender.CallEndpoint(context.Background(), Request{Payload: []byte("silent\n")}); result.Failed() || result.BytesReceived != 0 {
+		t.Fatalf("result=%+v, want the call to complete once sent", result)
+	}
+}
+
+func TestUDPClientReadsOneDatagram(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(append([]byte("ack:"), buffer[:n]...), from)
+		}
+	}()
+	client, err := New(Config{Network: "udp", Address: conn.LocalAddr().String(), AwaitResponse: true, KeepResponse: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := client.CallEndpoint(context.Background(), Request{Payload: []byte("<13>ping")}); result.Failed() || string(result.Response) != "ack:<13>ping" {
+		t.Fatalf("result=%+v, want the datagram reply", result)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []Config{{Network: "unix", Address: "x"}, {Network: "tcp"}, {Network: "udp", Address: "x", Timeout: -1}, {Network: "udp", Address: "x", TLS: &tls.Config{}}} {
+		if _, err := New(config); err == nil {
+			t.Fatalf("config=%+v, want it rejected", config)
+		}
+	}
+}
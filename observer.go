@@ -0,0 +1,31 @@
+package go_loadgen
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives the outcome of every measured request as it completes.
+// Observe is called concurrently from request goroutines and must not block.
+// Warm-up requests are not observed.
+type Observer interface {
+	Observe(Sample)
+}
+
+// Sample is the outcome of one request.
+type Sample struct {
+	Phase   PhaseInfo
+	Latency time.Duration
+	// Failed reports that the result implemented Outcome and failed.
+	Failed bool
+	// TimedOut reports that the request reached its RequestTimeout.
+	TimedOut bool
+}
+
+func (r *run) observe(ctx context.Context, completed completion) {
+	info, _ := PhaseFromContext(ctx)
+	sample := Sample{Phase: info, Latency: completed.latency, Failed: completed.failed, TimedOut: completed.timedOut}
+	for _, observer := range r.workload.observers {
+		observer.Observe(sample)
+	}
+}
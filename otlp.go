@@ -0,0 +1,333 @@
+package go_loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPExporter pushes per-phase request, failure, and timeout counts and
+// latency histograms to an OpenTelemetry collector every interval, using
+// OTLP/HTTP with JSON encoding. Pass it in Spec.Observers and Close it after
+// the run to push the final values. Data points carry the workload's
+// Spec.Name, so workloads sharing an exporter stay apart, and the phase's
+// tags as "tag."-prefixed attributes, which cannot collide with the built-in
+// ones. PrometheusCollector drops tags because label names must be valid
+// identifiers and should match across a metric's series; OTLP attribute keys
+// are free-form and may differ between points.
+type OTLPExporter struct {
+	metrics  *metricSet
+	endpoint string
+	client   *http.Client
+	headers  map[string]string
+	service  string
+	started  time.Time
+	cancel   context.CancelFunc
+	done     chan struct{}
+	close    sync.Once
+	errMu    sync.Mutex
+	err      error
+}
+
+// OTLPExporterOption configures an OTLPExporter.
+type OTLPExporterOption func(*otlpExporterConfig)
+
+type otlpExporterConfig struct {
+	client  *http.Client
+	headers map[string]string
+	service string
+	buckets []time.Duration
+}
+
+// WithOTLPHeaders adds headers, such as authentication, to every export.
+func WithOTLPHeaders(headers map[string]string) OTLPExporterOption {
+	return func(cfg *otlpExporterConfig) {
+		maps.Copy(cfg.headers, headers)
+	}
+}
+
+// WithOTLPHTTPClient sends exports with client instead of a client with a
+// ten second timeout.
+func WithOTLPHTTPClient(client *http.Client) OTLPExporterOption {
+	return func(cfg *otlpExporterConfig) {
+		if client != nil {
+			cfg.client = client
+		}
+	}
+}
+
+// WithOTLPServiceName sets the service.name resource attribute, "go-loadgen"
+// by default.
+func WithOTLPServiceName(name string) OTLPExporterOption {
+	return func(cfg *otlpExporterConfig) {
+		cfg.service = name
+	}
+}
+
+// WithOTLPBuckets sets the ascending latency histogram bucket bounds, which
+// default to DefaultLatencyBuckets.
+func WithOTLPBuckets(buckets ...time.Duration) OTLPExporterOption {
+	return func(cfg *otlpExporterConfig) {
+		cfg.buckets = buckets
+	}
+}
+
+// NewOTLPExporter creates an exporter that posts to endpoint, the collector's
+// full metrics URL such as http://localhost:4318/v1/metrics, every interval.
+func NewOTLPExporter(endpoint string, interval time.Duration, opts ...OTLPExporterOption) (*OTLPExporter, error) {
+	if endpoint == "" {
+		return nil, errors.New("otlp endpoint must be set")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("export interval must be positive")
+	}
+	cfg := otlpExporterConfig{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		headers: make(map[string]string),
+		service: "go-loadgen",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	metrics, err := newMetricSet(cfg.buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &OTLPExporter{
+		metrics:  metrics,
+		endpoint: endpoint,
+		client:   cfg.client,
+		headers:  cfg.headers,
+		service:  cfg.service,
+		started:  time.Now(),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go e.run(ctx, interval)
+
+	return e, nil
+}
+
+// Observe implements Observer.
+func (e *OTLPExporter) Observe(sample Sample) {
+	e.metrics.observe(sample)
+}
+
+// Close stops periodic exports, pushes the final values, and returns the
+// first export error observed by the exporter.
+func (e *OTLPExporter) Close() error {
+	e.close.Do(func() {
+		e.cancel()
+		<-e.done
+		e.setErr(e.export(context.Background()))
+	})
+	return e.Err()
+}
+
+// Err returns the first export error observed by the exporter.
+func (e *OTLPExporter) Err() error {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	return e.err
+}
+
+func (e *OTLPExporter) run(ctx context.Context, interval time.Duration) {
+	defer close(e.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			e.setErr(e.export(ctx))
+		}
+	}
+}
+
+func (e *OTLPExporter) export(ctx context.Context) error {
+	body, err := json.Marshal(e.request(time.Now()))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		request.Header.Set(key, value)
+	}
+	response, err := e.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: %s", response.Status)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) setErr(err error) {
+	if err == nil {
+		return
+	}
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// The types below are the OTLP/JSON encoding of an ExportMetricsServiceRequest.
+// 64-bit integers are strings and enums are numbers, as the encoding requires.
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Unit        string         `json:"unit"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+const otlpCumulative = 2
+
+type otlpSum struct {
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type otlpHistogramPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func (e *OTLPExporter) request(now time.Time) otlpRequest {
+	start, at := strconv.FormatInt(e.started.UnixNano(), 10), strconv.FormatInt(now.UnixNano(), 10)
+	bounds := make([]float64, len(e.metrics.buckets))
+	for i, bound := range e.metrics.buckets {
+		bounds[i] = bound.Seconds()
+	}
+	requests := &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+	failures := &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+	timeouts := &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+	latency := &otlpHistogram{AggregationTemporality: otlpCumulative}
+	for _, phase := range e.metrics.snapshot() {
+		attributes := []otlpAttribute{otlpString("workload", phase.info.Workload), otlpString("phase", phase.info.Name), otlpInt("phase.index", phase.info.Index)}
+		for _, key := range slices.Sorted(maps.Keys(phase.info.Tags)) {
+			attributes = append(attributes, otlpString("tag."+key, phase.info.Tags[key]))
+		}
+		point := func(value uint64) otlpNumberPoint {
+			return otlpNumberPoint{Attributes: attributes, StartTimeUnixNano: start, TimeUnixNano: at, AsInt: strconv.FormatUint(value, 10)}
+		}
+		count, buckets := phase.histogram()
+		requests.DataPoints = append(requests.DataPoints, point(count))
+		failures.DataPoints = append(failures.DataPoints, point(phase.failures.Load()))
+		timeouts.DataPoints = append(timeouts.DataPoints, point(phase.timeouts.Load()))
+
+		counts := make([]string, len(buckets)+1)
+		var bucketed uint64
+		for i, n := range buckets {
+			counts[i] = strconv.FormatUint(n, 10)
+			bucketed += n
+		}
+		counts[len(buckets)] = strconv.FormatUint(count-bucketed, 10)
+		latency.DataPoints = append(latency.DataPoints, otlpHistogramPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      at,
+			Count:             strconv.FormatUint(count, 10),
+			Sum:               time.Duration(phase.nanos.Load()).Seconds(),
+			BucketCounts:      counts,
+			ExplicitBounds:    bounds,
+		})
+	}
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{otlpString("service.name", e.service)}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope: otlpScope{Name: "github.com/luccadibe/go-loadgen"},
+			Metrics: []otlpMetric{
+				{Name: "loadgen.requests", Description: "Requests completed by phase.", Unit: "{request}", Sum: requests},
+				{Name: "loadgen.request.failures", Description: "Requests whose results reported failure.", Unit: "{request}", Sum: failures},
+				{Name: "loadgen.request.timeouts", Description: "Requests that reached their request timeout.", Unit: "{request}", Sum: timeouts},
+				{Name: "loadgen.request.duration", Description: "Request latency by phase.", Unit: "s", Histogram: latency},
+			},
+		}},
+	}}}
+}
@@ -0,0 +1,78 @@
+package go_loadgen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporterPushesPhaseMetrics(t *testing.T) {
+	var mu sync.Mutex
+	var last otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err := json.NewDecoder(r.Body).Decode(&last); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(server.URL+"/v1/metrics", time.Hour, WithOTLPHeaders(map[string]string{"Authorization": "Bearer token"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload := mustWorkload(t, Spec{
+		Name:      "api",
+		Duration:  time.Second,
+		Observers: []Observer{exporter},
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Name: "steady", Tags: map[string]string{"variant": "a", "phase": "shadowed"}, Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	metrics := last.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	requests, latency := metrics[0], metrics[3]
+	if requests.Name != "loadgen.requests" || len(requests.Sum.DataPoints) != 1 || requests.Sum.DataPoints[0].AsInt != strconv.FormatUint(report.Completed, 10) {
+		t.Fatalf("requests=%+v, want the %d completed requests", requests, report.Completed)
+	}
+	attributes := requests.Sum.DataPoints[0].Attributes
+	if len(attributes) != 5 || attributes[0].Key != "workload" || *attributes[0].Value.StringValue != "api" || *attributes[1].Value.StringValue != "steady" || *attributes[2].Value.IntValue != "0" || attributes[3].Key != "tag.phase" || attributes[4].Key != "tag.variant" {
+		t.Fatalf("attributes=%+v, want the workload, phase name, index, and prefixed tags", attributes)
+	}
+	point := latency.Histogram.DataPoints[0]
+	if latency.Name != "loadgen.request.duration" || len(point.BucketCounts) != len(DefaultLatencyBuckets)+1 || point.Count != requests.Sum.DataPoints[0].AsInt {
+		t.Fatalf("latency=%+v", latency)
+	}
+}
+
+func TestOTLPExporterReportsRejectedExports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(server.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Close(); err == nil {
+		t.Fatal("expected the rejected export to be reported")
+	}
+	if _, err := NewOTLPExporter(server.URL, time.Hour, WithOTLPBuckets(time.Second, time.Second)); err == nil {
+		t.Fatal("expected duplicate buckets to be rejected")
+	}
+}
@@ -0,0 +1,144 @@
+package go_loadgen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PhasePlan is the resolved schedule of one phase.
+type PhasePlan struct {
+	PhaseInfo
+	StartAt  time.Duration
+	Duration time.Duration
+	// RPS and EndRPS bound the offered rate, which moves between them for
+	// ramps, before any Noise. Both are zero for schedules without a rate.
+	RPS    uint64
+	EndRPS uint64
+	// Expected is the number of arrivals the schedule offers. Closed-loop
+	// phases offer at most Expected; unpaced users report zero because their
+	// rate depends on response times.
+	Expected uint64
+}
+
+// Plan is the resolved schedule of a workload, for reviewing it before a run.
+type Plan struct {
+	Duration time.Duration
+	Phases   []PhasePlan
+	Expected uint64
+}
+
+// Plan resolves the workload's schedule without sending any traffic. It walks
+// every phase's pacer, so Expected is exact for seeded Poisson phases too.
+// Rates set with SetRate are included.
+func (w *Workload) Plan() Plan {
+	plan := Plan{Duration: w.duration, Phases: make([]PhasePlan, len(w.phases))}
+	for i := range w.phases {
+		phase := &w.phases[i]
+		planned := PhasePlan{PhaseInfo: phase.info, StartAt: phase.phase.StartAt, Duration: phase.phase.Duration}
+		if phase.phase.hasRate() {
+			planned.RPS = phase.nominalRateAt(0)
+			planned.EndRPS = phase.nominalRateAt(phase.phase.Duration)
+			planned.PhaseInfo.RPS = planned.RPS
+		}
+		if !phase.phase.Idle && (phase.phase.Users == 0 || phase.phase.RPS != 0) {
+			planned.Expected = phase.expected()
+		}
+		plan.Phases[i] = planned
+		plan.Expected += planned.Expected
+	}
+	return plan
+}
+
+// expected counts the arrivals the phase's pacer offers.
+func (p *compiledPhase) expected() uint64 {
+	pacer := p.newPacer()
+	if pacer == nil {
+		return 0
+	}
+	var arrivals uint64
+	for {
+		batch, ok := pacer.Next()
+		if !ok || batch.At > p.phase.Duration {
+			return arrivals
+		}
+		arrivals += batch.Count
+	}
+}
+
+// String formats the plan as a table with one row per phase.
+func (p Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "duration %s, %d expected arrivals\n", p.Duration, p.Expected)
+	for _, phase := range p.Phases {
+		fmt.Fprintf(&b, "%3d %-12s %-8s start %-8s for %-8s", phase.Index, phase.Name, phase.Kind, phase.StartAt, phase.Duration)
+		switch {
+		case phase.RPS != phase.EndRPS:
+			fmt.Fprintf(&b, " %d -> %d rps", phase.RPS, phase.EndRPS)
+		case phase.RPS != 0:
+			fmt.Fprintf(&b, " %d rps", phase.RPS)
+		}
+		fmt.Fprintf(&b, " expected %d\n", phase.Expected)
+	}
+	return b.String()
+}
+
+// Chart plots the plan's combined offered rate over time as ASCII art, width
+// columns by height rows, for checking a workload's shape before running it.
+// Ramps are drawn linearly, and schedules without a rate, such as bursts and
+// traces, at their average rate.
+func (p Plan) Chart(width, height int) string {
+	width, height = max(width, 1), max(height, 1)
+	rates := make([]float64, width)
+	var peak float64
+	for i := range rates {
+		at := p.Duration * time.Duration(2*i+1) / time.Duration(2*width)
+		rates[i] = p.rateAt(at)
+		peak = max(peak, rates[i])
+	}
+	top := strconv.FormatFloat(math.Ceil(peak), 'f', -1, 64)
+	label := len(top)
+	var b strings.Builder
+	for row := range height {
+		switch row {
+		case 0:
+			fmt.Fprintf(&b, "%*s |", label, top)
+		default:
+			fmt.Fprintf(&b, "%*s |", label, "")
+		}
+		var line strings.Builder
+		for _, rate := range rates {
+			if peak > 0 && math.Round(rate/peak*float64(height)) >= float64(height-row) {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte(' ')
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%*s +%s\n", label, "0", strings.Repeat("-", width))
+	end := p.Duration.String()
+	fmt.Fprintf(&b, "%*s  0s%*s\n", label, "", max(width-2, len(end)+1), end)
+	return b.String()
+}
+
+// rateAt is the combined offered rate of the phases active at offset.
+func (p Plan) rateAt(offset time.Duration) float64 {
+	var rate float64
+	for _, phase := range p.Phases {
+		if offset < phase.StartAt || offset >= phase.StartAt+phase.Duration {
+			continue
+		}
+		switch {
+		case phase.RPS != 0 || phase.EndRPS != 0:
+			progress := float64(offset-phase.StartAt) / float64(phase.Duration)
+			rate += float64(phase.RPS) + (float64(phase.EndRPS)-float64(phase.RPS))*progress
+		case phase.Expected != 0:
+			rate += float64(phase.Expected) / phase.Duration.Seconds()
+		}
+	}
+	return rate
+}
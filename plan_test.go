@@ -0,0 +1,54 @@
+package go_loadgen
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanResolvesScheduleWithoutTraffic(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  10 * time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases: []Phase{
+			{Name: "ramp", Duration: 4 * time.Second, RPS: 100, Ramp: &Ramp{To: 400, Step: 100, Every: time.Second}, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Name: "spike", StartAt: 4 * time.Second, Duration: time.Second, Burst: &Burst{Size: 50, Every: 100 * time.Millisecond}, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Name: "users", StartAt: 5 * time.Second, Duration: time.Second, Users: 4, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+
+	plan := workload.Plan()
+	if endpoint.count.Load() != 0 {
+		t.Fatal("plan sent traffic")
+	}
+	ramp, spike, users := plan.Phases[0], plan.Phases[1], plan.Phases[2]
+	if ramp.Kind != "uniform" || ramp.RPS != 100 || ramp.EndRPS != 400 || ramp.Expected < 995 || ramp.Expected > 1000 {
+		t.Fatalf("ramp=%+v, want 100 to 400 rps and about 1000 arrivals", ramp)
+	}
+	if spike.Kind != "burst" || spike.Expected != 500 || users.Expected != 0 || plan.Expected != ramp.Expected+500 {
+		t.Fatalf("spike=%+v users=%+v expected=%d", spike, users, plan.Expected)
+	}
+	if text := plan.String(); !strings.Contains(text, "100 -> 400 rps") || !strings.Contains(text, "expected 500") {
+		t.Fatalf("plan text:\n%s", text)
+	}
+}
+
+func TestPlanChartDrawsCombinedRate(t *testing.T) {
+	plan := Plan{
+		Duration: 8 * time.Second,
+		Phases: []PhasePlan{
+			{StartAt: 0, Duration: 4 * time.Second, RPS: 100, EndRPS: 100},
+			{StartAt: 2 * time.Second, Duration: 2 * time.Second, Expected: 200},
+			{StartAt: 6 * time.Second, Duration: 2 * time.Second, RPS: 0, EndRPS: 200},
+		},
+	}
+	want := "" +
+		"200 |  ##   #\n" +
+		"    |####  ##\n" +
+		"  0 +--------\n" +
+		"     0s    8s\n"
+	if got := plan.Chart(8, 2); got != want {
+		t.Fatalf("chart:\n%s\nwant:\n%s", got, want)
+	}
+}
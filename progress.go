@@ -0,0 +1,76 @@
+package go_loadgen
+
+import "time"
+
+// ProgressKind identifies a ProgressEvent.
+type ProgressKind uint8
+
+const (
+	// ProgressTick is sent every Spec.ProgressEvery while a run is active.
+	ProgressTick ProgressKind = iota
+	// PhaseStarted is sent when a phase begins scheduling.
+	PhaseStarted
+	// PhaseFinished is sent when a phase stops scheduling. Its requests may
+	// still be outstanding.
+	PhaseFinished
+)
+
+// ProgressEvent is a snapshot of a run in progress.
+type ProgressEvent struct {
+	Kind ProgressKind
+	// Phase is the index of the phase that started or finished.
+	Phase int
+	// Elapsed is the run's schedule time, excluding pauses, and Percent is its
+	// share of Spec.Duration.
+	Elapsed time.Duration
+	Percent float64
+	// Counts are the run's totals so far.
+	Counts Counts
+	// IssuedPerSecond is the rate of issued requests since the previous tick.
+	IssuedPerSecond float64
+}
+
+// notify sends a progress event without blocking; events are dropped while
+// the consumer is behind.
+func (r *run) notify(event ProgressEvent) {
+	if r.workload.progress == nil {
+		return
+	}
+	event.Elapsed = r.elapsed()
+	event.Percent = min(100*float64(event.Elapsed)/float64(r.workload.duration), 100)
+	event.Counts = r.counts()
+	select {
+	case r.workload.progress <- event:
+	default:
+	}
+}
+
+// counts totals the run's phase counts so far.
+func (r *run) counts() Counts {
+	var counts Counts
+	for i := range r.phases {
+		counts.add(r.phases[i].counts.load())
+	}
+	return counts
+}
+
+// reportProgress sends ticks until done is closed.
+func (r *run) reportProgress(done <-chan struct{}) {
+	ticker := r.workload.clock.NewTicker(r.workload.progressEvery)
+	defer ticker.Stop()
+	var issued uint64
+	last := r.workload.clock.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C():
+			var total uint64
+			for i := range r.phases {
+				total += r.phases[i].counts.issued.Load()
+			}
+			r.notify(ProgressEvent{Kind: ProgressTick, IssuedPerSecond: float64(total-issued) / now.Sub(last).Seconds()})
+			issued, last = total, now
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package go_loadgen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressReportsPhasesAndTicks(t *testing.T) {
+	events := make(chan ProgressEvent, 64)
+	workload := mustWorkload(t, Spec{
+		Duration:      100 * time.Millisecond,
+		Progress:      events,
+		ProgressEvery: 20 * time.Millisecond,
+		Endpoints:     map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases: []Phase{
+			{Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{StartAt: 50 * time.Millisecond, Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+
+	report := mustRun(t, workload)
+	close(events)
+	var started, finished, ticks int
+	var last ProgressEvent
+	var rate float64
+	for event := range events {
+		switch event.Kind {
+		case PhaseStarted:
+			started++
+		case PhaseFinished:
+			finished++
+		case ProgressTick:
+			ticks++
+			rate = max(rate, event.IssuedPerSecond)
+			if event.Counts.Issued < last.Counts.Issued || event.Percent < last.Percent {
+				t.Fatalf("tick=%+v after %+v, want increasing progress", event, last)
+			}
+			last = event
+		}
+	}
+	if started != 2 || finished != 2 || ticks < 2 || rate == 0 {
+		t.Fatalf("started=%d finished=%d ticks=%d rate=%f", started, finished, ticks, rate)
+	}
+	if last.Counts.Issued > report.Issued || last.Percent > 100 {
+		t.Fatalf("last tick=%+v report issued=%d", last, report.Issued)
+	}
+}
@@ -0,0 +1,79 @@
+package go_loadgen
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusCollector keeps per-phase request counters and latency histograms
+// and serves them in the Prometheus text format while a run is in progress, so
+// an experiment can be watched live next to the target's own metrics. Pass it
+// in Spec.Observers and mount it as the /metrics handler. Series are labelled
+// with the workload's Spec.Name, so workloads sharing a collector stay apart.
+// Phase tags are not exported; OTLPExporter carries them.
+type PrometheusCollector struct {
+	metrics *metricSet
+}
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// NewPrometheusCollector creates a collector with the given ascending latency
+// bucket bounds, or DefaultLatencyBuckets when none are given.
+func NewPrometheusCollector(buckets ...time.Duration) (*PrometheusCollector, error) {
+	metrics, err := newMetricSet(buckets)
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusCollector{metrics: metrics}, nil
+}
+
+// Observe implements Observer.
+func (c *PrometheusCollector) Observe(sample Sample) {
+	c.metrics.observe(sample)
+}
+
+// ServeHTTP writes the current metrics in the Prometheus text format.
+func (c *PrometheusCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the current metrics in the Prometheus text format.
+func (c *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	phases := c.metrics.snapshot()
+	labels := make([]string, len(phases))
+	for i, phase := range phases {
+		labels[i] = fmt.Sprintf(`workload="%s",phase="%s",index="%d"`, prometheusEscaper.Replace(phase.info.Workload), prometheusEscaper.Replace(phase.info.Name), phase.info.Index)
+	}
+
+	var b strings.Builder
+	counter := func(name, help string, value func(*phaseMetrics) uint64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for i, phase := range phases {
+			fmt.Fprintf(&b, "%s{%s} %d\n", name, labels[i], value(phase))
+		}
+	}
+	counter("loadgen_requests_total", "Requests completed by phase.", func(p *phaseMetrics) uint64 { return p.requests.Load() })
+	counter("loadgen_request_failures_total", "Requests whose results reported failure.", func(p *phaseMetrics) uint64 { return p.failures.Load() })
+	counter("loadgen_request_timeouts_total", "Requests that reached their request timeout.", func(p *phaseMetrics) uint64 { return p.timeouts.Load() })
+
+	const histogram = "loadgen_request_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Request latency by phase.\n# TYPE %s histogram\n", histogram, histogram)
+	for i, phase := range phases {
+		count, buckets := phase.histogram()
+		var cumulative uint64
+		for j, bound := range c.metrics.buckets {
+			cumulative += buckets[j]
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", histogram, labels[i], strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogram, labels[i], count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", histogram, labels[i], strconv.FormatFloat(time.Duration(phase.nanos.Load()).Seconds(), 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", histogram, labels[i], count)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
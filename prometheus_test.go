@@ -0,0 +1,65 @@
+package go_loadgen
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusCollectorExportsPhaseMetrics(t *testing.T) {
+	collector, err := NewPrometheusCollector(time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		info, _ := PhaseFromContext(ctx)
+		return testResult{failed: info.Index == 1}
+	})
+	workload := mustWorkload(t, Spec{
+		Name:      "api",
+		Duration:  time.Second,
+		Observers: []Observer{collector},
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases: []Phase{
+			{Name: "steady", Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Name: `say "hi"`, StartAt: 20 * time.Millisecond, Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+	report := mustRun(t, workload)
+	// A second workload sharing the collector gets its own series.
+	other := mustRun(t, mustWorkload(t, Spec{
+		Name:      "batch",
+		Duration:  time.Second,
+		Observers: []Observer{collector},
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Name: "steady", Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	}))
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, want := range []string{
+		"# TYPE loadgen_requests_total counter",
+		fmt.Sprintf(`loadgen_requests_total{workload="api",phase="steady",index="0"} %d`, report.Phases[0].Completed),
+		fmt.Sprintf(`loadgen_request_failures_total{workload="api",phase="say \"hi\"",index="1"} %d`, report.Phases[1].Completed),
+		`loadgen_request_failures_total{workload="api",phase="steady",index="0"} 0`,
+		fmt.Sprintf(`loadgen_requests_total{workload="batch",phase="steady",index="0"} %d`, other.Phases[0].Completed),
+		"# TYPE loadgen_request_duration_seconds histogram",
+		fmt.Sprintf(`loadgen_request_duration_seconds_bucket{workload="api",phase="steady",index="0",le="1"} %d`, report.Phases[0].Completed),
+		fmt.Sprintf(`loadgen_request_duration_seconds_bucket{workload="api",phase="steady",index="0",le="+Inf"} %d`, report.Phases[0].Completed),
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Fatalf("content type=%q", got)
+	}
+
+	if _, err := NewPrometheusCollector(time.Second, time.Millisecond); err == nil {
+		t.Fatal("expected descending buckets to be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Step is one request of a scenario. Call reads and updates the iteration's
+// state, which is how data such as session tokens flows between steps.
+type Step[S any] struct {
+	Name string
+	Call func(ctx context.Context, state *S) error
+	// Think is the pause after the step before the next one, modelling the
+	// time a person spends on a page. It counts towards the iteration latency.
+	// Random think times follow Spec.Seed, so repeated runs pause alike.
+	Think ThinkTime
+}
+
+// StepResult is the outcome of one step.
+type StepResult struct {
+	Name    string
+	Latency time.Duration
+	Err     error
+}
+
+// ScenarioResult is the outcome of one scenario iteration. Steps after a
+// failed one are skipped, so Steps ends with the failure.
+type ScenarioResult[S any] struct {
+	State   S
+	Steps   []StepResult
+	Latency time.Duration
+	// Err is the failing step's error, if any.
+	Err error
+}
+
+// Failed implements Outcome.
+func (r ScenarioResult[S]) Failed() bool { return r.Err != nil }
+
+// Scenario is a user journey such as login, browse, and checkout, run as one
+// iteration per arrival. It is a Client of its initial state, so scenarios are
+// used with NewEndpoint like any other client; the data provider creates each
+// iteration's initial state.
+type Scenario[S any] struct {
+	steps []Step[S]
+}
+
+// NewScenario returns a scenario running steps in order.
+func NewScenario[S any](steps ...Step[S]) (*Scenario[S], error) {
+	if len(steps) == 0 {
+		return nil, errors.New("scenario must contain at least one step")
+	}
+	for i, step := range steps {
+		if step.Call == nil {
+			return nil, fmt.Errorf("scenario step %d has no call", i)
+		}
+		if err := step.Think.validate(); err != nil {
+			return nil, fmt.Errorf("scenario step %d: %w", i, err)
+		}
+	}
+	return &Scenario[S]{steps: slices.Clone(steps)}, nil
+}
+
+// CallEndpoint implements Client by running one iteration.
+func (s *Scenario[S]) CallEndpoint(ctx context.Context, state S) ScenarioResult[S] {
+	result := ScenarioResult[S]{Steps: make([]StepResult, 0, len(s.steps))}
+	started := time.Now()
+	random := randomFromContext(ctx)
+	for i, step := range s.steps {
+		stepStarted := time.Now()
+		err := step.Call(ctx, &state)
+		result.Steps = append(result.Steps, StepResult{Name: step.Name, Latency: time.Since(stepStarted), Err: err})
+		if err != nil {
+			result.Err = fmt.Errorf("step %q: %w", step.Name, err)
+			break
+		}
+		if think := step.Think.sample(&random); think > 0 && i < len(s.steps)-1 && !sleep(ctx, think) {
+			result.Err = fmt.Errorf("step %q: %w", step.Name, ctx.Err())
+			break
+		}
+	}
+	result.Latency = time.Since(started)
+	result.State = state
+	return result
+}
@@ -0,0 +1,154 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type checkout struct {
+	user  string
+	token string
+	items int
+}
+
+type checkoutProvider struct{}
+
+func (checkoutProvider) GetData() checkout { return checkout{user: "alice"} }
+
+type checkoutCollector struct{ results chan ScenarioResult[checkout] }
+
+func (c checkoutCollector) Collect(result ScenarioResult[checkout]) { c.results <- result }
+func (checkoutCollector) Close()                                    {}
+
+func TestScenarioPassesStateBetweenSteps(t *testing.T) {
+	errSoldOut := errors.New("sold out")
+	scenario, err := NewScenario(
+		Step[checkout]{Name: "login", Call: func(_ context.Context, state *checkout) error {
+			state.token = "token-" + state.user
+			return nil
+		}},
+		Step[checkout]{Name: "browse", Call: func(_ context.Context, state *checkout) error {
+			if state.token == "" {
+				return errors.New("not logged in")
+			}
+			state.items = 2
+			return nil
+		}},
+		Step[checkout]{Name: "checkout", Call: func(context.Context, *checkout) error { return errSoldOut }},
+		Step[checkout]{Name: "confirm", Call: func(context.Context, *checkout) error { return nil }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := checkoutCollector{results: make(chan ScenarioResult[checkout], 16)}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"journey": mustEndpoint(t, scenario, checkoutProvider{}, collector)},
+		Phases:    []Phase{{Duration: 10 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "journey", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if report.Completed == 0 || report.Failed != report.Completed {
+		t.Fatalf("completed=%d failed=%d, want every iteration to fail at checkout", report.Completed, report.Failed)
+	}
+	result := <-collector.results
+	if result.State.token != "token-alice" || result.State.items != 2 || len(result.Steps) != 3 || !errors.Is(result.Err, errSoldOut) {
+		t.Fatalf("result=%+v, want state carried through and steps stopped at checkout", result)
+	}
+	if _, err := NewScenario[checkout](); err == nil {
+		t.Fatal("expected an empty scenario to be rejected")
+	}
+}
+
+func TestScenarioThinksBetweenSteps(t *testing.T) {
+	step := func(context.Context, *checkout) error { return nil }
+	scenario, err := NewScenario(
+		Step[checkout]{Name: "browse", Call: step, Think: ThinkTime{Distribution: UniformThink, Duration: 10 * time.Millisecond, Max: 15 * time.Millisecond}},
+		Step[checkout]{Name: "buy", Call: step, Think: ThinkTime{Duration: time.Hour}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := scenario.CallEndpoint(context.Background(), checkout{})
+	if result.Err != nil || result.Latency < 10*time.Millisecond || result.Latency > time.Second {
+		t.Fatalf("result=%+v, want one think between the steps and none after the last", result)
+	}
+
+	// Cancelling during the first step leaves the think as the only wait.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelling, err := NewScenario(
+		Step[checkout]{Name: "browse", Call: func(context.Context, *checkout) error { cancel(); return nil }, Think: ThinkTime{Duration: time.Hour}},
+		Step[checkout]{Name: "buy", Call: step},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := cancelling.CallEndpoint(ctx, checkout{}); !errors.Is(result.Err, context.Canceled) || len(result.Steps) != 1 {
+		t.Fatalf("result=%+v, want thinking cut short by the context", result)
+	}
+}
+
+func TestScenarioThinkTimesFollowSeed(t *testing.T) {
+	think := ThinkTime{Distribution: ExponentialThink, Duration: time.Second}
+	draws := func(spec Spec) []time.Duration {
+		var mu sync.Mutex
+		var sampled []time.Duration
+		client := testClient(func(ctx context.Context, _ testRequest) testResult {
+			random := randomFromContext(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			sampled = append(sampled, think.sample(&random))
+			return testResult{}
+		})
+		spec.Endpoints = map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})}
+		mustRun(t, mustWorkload(t, spec))
+		slices.Sort(sampled)
+		return sampled
+	}
+	spec := Spec{Seed: 7, Duration: 30 * time.Millisecond, Phases: []Phase{{Duration: 30 * time.Millisecond, RPS: 200, Targets: []Target{{Endpoint: "one", Weight: 1}}}}}
+	first, second := draws(spec), draws(spec)
+	// A loaded machine may miss an arrival, so only those issued both times
+	// are compared.
+	if len(second) == 0 {
+		t.Fatal("workload issued no requests")
+	}
+	for _, draw := range second {
+		if !slices.Contains(first, draw) {
+			t.Fatalf("draws=%v and %v, want the same think times from the same seed", first, second)
+		}
+	}
+	spec.Seed = 8
+	if other := draws(spec); len(other) != 0 && slices.Contains(first, other[0]) {
+		t.Fatal("expected another seed to draw other think times")
+	}
+}
+
+func TestBatchedRequestsDrawIndependently(t *testing.T) {
+	var mu sync.Mutex
+	var seeds []uint64
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		random := randomFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		seeds = append(seeds, random.state)
+		return testResult{}
+	})
+	// A burst issues its arrivals as one batch.
+	mustRun(t, mustWorkload(t, Spec{
+		Seed:      7,
+		Duration:  20 * time.Millisecond,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 20 * time.Millisecond, Burst: &Burst{Size: 5, Every: time.Second}, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	}))
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(seeds)
+	if len(seeds) != 5 || len(slices.Compact(slices.Clone(seeds))) != 5 {
+		t.Fatalf("seeds=%v, want a distinct seed for each request of the burst", seeds)
+	}
+}
@@ -0,0 +1,33 @@
+package go_loadgen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ErrInterrupted is returned by Run when Spec.HandleSignals stopped it.
+var ErrInterrupted = errors.New("interrupted")
+
+// stopOnSignal stops scheduling on SIGINT or SIGTERM and leaves outstanding
+// requests to drain. Later signals get their default behaviour, so a second
+// Ctrl-C still terminates the process. The returned function removes the handler.
+func (r *run) stopOnSignal() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case received := <-signals:
+			signal.Stop(signals)
+			r.abort(fmt.Errorf("%w: %v", ErrInterrupted, received))
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
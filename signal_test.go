@@ -0,0 +1,39 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHandleSignalsDrainsAfterInterrupt(t *testing.T) {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Skip(err)
+	}
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(20 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:      time.Second,
+		HandleSignals: true,
+		Endpoints:     map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:        []Phase{{Duration: time.Second, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	time.AfterFunc(30*time.Millisecond, func() {
+		if err := process.Signal(os.Interrupt); err != nil {
+			t.Error(err)
+		}
+	})
+	report, err := workload.Run(context.Background())
+	if !errors.Is(err, ErrInterrupted) || report.SchedulingDuration > 500*time.Millisecond {
+		t.Fatalf("err=%v scheduling=%s, want the run interrupted", err, report.SchedulingDuration)
+	}
+	if report.Issued == 0 || report.Completed != report.Issued {
+		t.Fatalf("issued=%d completed=%d, want outstanding requests drained", report.Issued, report.Completed)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Package sqlclient is a go_loadgen client that runs parameterized queries
+through database/sql, for load testing Postgres, MySQL, and other databases
+with any registered driver.
+
+The data provider supplies each Request's query and arguments. A Client reads
+every returned row, so a Result's latency covers transferring the whole result
+set, and records the row count and any driver error.
+*/
+package sqlclient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Config holds the database handle and its pool limits. Zero limits keep the
+// handle's current settings.
+type Config struct {
+	// DB is an open handle, such as from sql.Open("pgx", dsn). The client
+	// does not close it.
+	DB *sql.DB
+	// Query is run for requests that set none.
+	Query           string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Request is one statement. Exec runs it without reading rows, for inserts and
+// updates, and reports the rows affected instead.
+type Request struct {
+	Query string
+	Args  []any
+	Exec  bool
+}
+
+// Result is the measurement of one statement. Rows counts the rows read or,
+// for Exec requests, the rows affected when the driver reports them.
+type Result struct {
+	Latency time.Duration
+	Rows    int64
+	// TimedOut reports statements that hit a deadline.
+	TimedOut bool
+	Error    string
+}
+
+// Failed implements go_loadgen.Outcome.
+func (r Result) Failed() bool { return r.Error != "" }
+
+// CSVHeaders implements go_loadgen.CSVSerializable.
+func (r Result) CSVHeaders() []string {
+	return []string{"latency_ms", "rows", "timed_out", "error"}
+}
+
+// CSVRecord implements go_loadgen.CSVSerializable.
+func (r Result) CSVRecord() []string {
+	return []string{
+		strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.FormatInt(r.Rows, 10),
+		strconv.FormatBool(r.TimedOut),
+		r.Error,
+	}
+}
+
+// Client implements go_loadgen.Client[Request, Result].
+type Client struct {
+	db    *sql.DB
+	query string
+}
+
+// New creates a client from config and applies its pool limits to the handle.
+func New(config Config) (*Client, error) {
+	if config.DB == nil {
+		return nil, errors.New("database handle must be non-nil")
+	}
+	if config.MaxOpenConns < 0 || config.MaxIdleConns < 0 || config.ConnMaxLifetime < 0 || config.ConnMaxIdleTime < 0 {
+		return nil, errors.New("pool limits cannot be negative")
+	}
+	if config.MaxOpenConns != 0 {
+		config.DB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns != 0 {
+		config.DB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		config.DB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		config.DB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+	return &Client{db: config.DB, query: config.Query}, nil
+}
+
+// CallEndpoint implements go_loadgen.Client.
+func (c *Client) CallEndpoint(ctx context.Context, request Request) Result {
+	query := request.Query
+	if query == "" {
+		query = c.query
+	}
+	started := time.Now()
+	var result Result
+	var err error
+	if request.Exec {
+		result.Rows, err = c.exec(ctx, query, request.Args)
+	} else {
+		result.Rows, err = c.read(ctx, query, request.Args)
+	}
+	result.Latency = time.Since(started)
+	if err != nil {
+		result.TimedOut = errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Client) exec(ctx context.Context, query string, args []any) (int64, error) {
+	executed, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	// Drivers that cannot count affected rows still succeed.
+	affected, err := executed.RowsAffected()
+	if err != nil {
+		return 0, nil
+	}
+	return affected, nil
+}
+
+func (c *Client) read(ctx context.Context, query string, args []any) (int64, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var count int64
+	for rows.Next() {
+		count++
+	}
+	return count, rows.Err()
+}
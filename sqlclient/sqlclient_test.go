@@ -0,0 +1,122 @@
+package sqlclient
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDriver answers "rows" queries with as many rows as their first argument
+// and "slow" queries only after their context ends.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("unsupported") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("context required")
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	switch s.query {
+	case "rows":
+		return &fakeRows{left: args[0].Value.(int64)}, nil
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("syntax error")
+}
+
+type fakeRows struct{ left int64 }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	dest[0] = r.left
+	r.left--
+	return nil
+}
+
+func init() { sql.Register("sqlclient-fake", fakeDriver{}) }
+
+func newClient(t *testing.T, config Config) *Client {
+	t.Helper()
+	db, err := sql.Open("sqlclient-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	config.DB = db
+	client, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientCountsRowsAndReportsErrors(t *testing.T) {
+	client := newClient(t, Config{Query: "rows", MaxOpenConns: 4})
+	if stats := client.db.Stats(); stats.MaxOpenConnections != 4 {
+		t.Fatalf("max open=%d, want the pool limit applied", stats.MaxOpenConnections)
+	}
+	result := client.CallEndpoint(context.Background(), Request{Args: []any{3}})
+	if result.Failed() || result.Rows != 3 || result.Latency <= 0 {
+		t.Fatalf("result=%+v, want three rows from the default query", result)
+	}
+	if result = client.CallEndpoint(context.Background(), Request{Query: "insert", Args: []any{1, 2}, Exec: true}); result.Failed() || result.Rows != 2 {
+		t.Fatalf("result=%+v, want two rows affected", result)
+	}
+	if result = client.CallEndpoint(context.Background(), Request{Query: "bad"}); !result.Failed() || !strings.Contains(result.Error, "syntax") || result.TimedOut {
+		t.Fatalf("result=%+v, want the driver error", result)
+	}
+	if record := result.CSVRecord(); len(record) != len(result.CSVHeaders()) || record[3] != "syntax error" {
+		t.Fatalf("record=%v", record)
+	}
+}
+
+func TestClientReportsTimeouts(t *testing.T) {
+	client := newClient(t, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if result := client.CallEndpoint(ctx, Request{Query: "slow"}); !result.TimedOut || !result.Failed() {
+		t.Fatalf("result=%+v, want a timeout", result)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Fatal("expected a missing handle to be rejected")
+	}
+	db, err := sql.Open("sqlclient-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := New(Config{DB: db, MaxIdleConns: -1}); err == nil {
+		t.Fatal("expected a negative pool limit to be rejected")
+	}
+}
@@ -0,0 +1,129 @@
+package go_loadgen
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStopCondition is returned by Run when a StopCondition stopped it.
+var ErrStopCondition = errors.New("stop condition tripped")
+
+// StopCondition stops the whole run when the results of roughly its last
+// Window breach a limit, protecting shared environments from runaway tests.
+// Outstanding requests still drain. Warm-up results are not evaluated.
+type StopCondition struct {
+	// Window rotates in ten slots, so it must be at least ten nanoseconds.
+	Window time.Duration
+	// MaxErrorRate is the tolerated fraction of failed results. Zero disables it.
+	MaxErrorRate float64
+	// MaxLatency is the tolerated response time at Percentile, measured around
+	// each client call. Zero disables it; zero Percentile uses 99.
+	MaxLatency time.Duration
+	Percentile float64
+	// MinRequests is the number of results a window needs before it is
+	// evaluated, so a single early failure does not stop the run.
+	MinRequests uint64
+}
+
+func (c StopCondition) validate() error {
+	// The window rotates through stopWindowSlots ticks, which must be positive.
+	if c.Window < stopWindowSlots || c.MaxLatency < 0 || c.MaxErrorRate < 0 || c.MaxErrorRate > 1 || c.Percentile < 0 || c.Percentile >= 100 {
+		return errors.New("stop condition needs a positive window, an error rate between zero and one, and a percentile below 100")
+	}
+	if c.MaxErrorRate == 0 && c.MaxLatency == 0 {
+		return errors.New("stop condition must limit the error rate or latency")
+	}
+	return nil
+}
+
+// stopWindowSlots is the number of slots a stop condition's window rotates
+// through; evaluation happens once per slot.
+const stopWindowSlots = 10
+
+type stopWindow struct {
+	condition StopCondition
+	current   atomic.Uint64
+	slots     [stopWindowSlots]stopWindowSlot
+}
+
+type stopWindowSlot struct {
+	requests  atomic.Uint64
+	failed    atomic.Uint64
+	latencies histogram
+}
+
+func (w *stopWindow) init(condition StopCondition) {
+	w.condition = condition
+	for i := range w.slots {
+		w.slots[i].latencies = newHistogram(defaultHistogramSubBits)
+	}
+}
+
+func (w *stopWindow) record(done completion) {
+	slot := &w.slots[w.current.Load()%stopWindowSlots]
+	slot.requests.Add(1)
+	if done.failed {
+		slot.failed.Add(1)
+	}
+	if w.condition.MaxLatency > 0 {
+		slot.latencies.record(done.latency)
+	}
+}
+
+// rotate starts a new slot, dropping the oldest one from the window.
+func (w *stopWindow) rotate() {
+	next := w.current.Load() + 1
+	slot := &w.slots[next%stopWindowSlots]
+	slot.requests.Store(0)
+	slot.failed.Store(0)
+	slot.latencies.reset()
+	w.current.Store(next)
+}
+
+// breach describes how the window violates its condition, or returns nil.
+func (w *stopWindow) breach() error {
+	condition := w.condition
+	var requests, failed uint64
+	var window latencies
+	for i := range w.slots {
+		requests += w.slots[i].requests.Load()
+		failed += w.slots[i].failed.Load()
+		if condition.MaxLatency > 0 {
+			window.add(&w.slots[i].latencies)
+		}
+	}
+	if requests == 0 || requests < condition.MinRequests {
+		return nil
+	}
+	if rate := float64(failed) / float64(requests); condition.MaxErrorRate > 0 && rate > condition.MaxErrorRate {
+		return fmt.Errorf("%w: error rate %.3f over %s", ErrStopCondition, rate, condition.Window)
+	}
+	percentile := condition.Percentile
+	if percentile == 0 {
+		percentile = 99
+	}
+	if latency := window.percentile(percentile); condition.MaxLatency > 0 && latency > condition.MaxLatency {
+		return fmt.Errorf("%w: p%g latency %s over %s", ErrStopCondition, percentile, latency, condition.Window)
+	}
+	return nil
+}
+
+// watch evaluates the condition once per slot until done is closed.
+func (r *run) watch(window *stopWindow, done <-chan struct{}) {
+	ticker := r.workload.clock.NewTicker(window.condition.Window / stopWindowSlots)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+			if err := window.breach(); err != nil {
+				r.abort(err)
+				return
+			}
+			window.rotate()
+		}
+	}
+}
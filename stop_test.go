@@ -0,0 +1,66 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentileIsAccurate(t *testing.T) {
+	h := newHistogram(defaultHistogramSubBits)
+	for i := 1; i <= 10_000; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+	var snapshot latencies
+	snapshot.add(&h)
+	for _, tc := range []struct {
+		percentile float64
+		want       time.Duration
+	}{{50, 5 * time.Millisecond}, {99, 9900 * time.Microsecond}, {100, 10 * time.Millisecond}} {
+		got := snapshot.percentile(tc.percentile)
+		if got < tc.want*94/100 || got > tc.want*106/100 {
+			t.Fatalf("p%g=%s, want within 6%% of %s", tc.percentile, got, tc.want)
+		}
+	}
+}
+
+func TestStopConditionStopsSlowRun(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(5 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:       time.Second,
+		StopConditions: []StopCondition{{Window: 50 * time.Millisecond, MaxLatency: time.Millisecond, MinRequests: 5}},
+		Endpoints:      map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:         []Phase{{Duration: time.Second, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report, err := workload.Run(context.Background())
+	if !errors.Is(err, ErrStopCondition) || report.SchedulingDuration > 500*time.Millisecond {
+		t.Fatalf("err=%v scheduling=%s, want the run stopped by latency", err, report.SchedulingDuration)
+	}
+}
+
+func TestStopConditionToleratesHealthyRun(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:       100 * time.Millisecond,
+		StopConditions: []StopCondition{{Window: 20 * time.Millisecond, MaxErrorRate: 0.01, MaxLatency: time.Second}},
+		Endpoints:      map[string]Endpoint{"one": mustEndpoint(t, testClient(func(context.Context, testRequest) testResult { return testResult{} }), testProvider{}, &testCollector{})},
+		Phases:         []Phase{{Duration: 100 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	mustRun(t, workload)
+}
+
+func TestStopConditionRejectsWindowTooShortToSplit(t *testing.T) {
+	_, err := NewWorkload(Spec{
+		Duration:       time.Second,
+		StopConditions: []StopCondition{{Window: 5, MaxErrorRate: 0.1}},
+		Endpoints:      map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:         []Phase{{Duration: time.Second, RPS: 1, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	if err == nil {
+		t.Fatal("expected a window shorter than its slots to be rejected")
+	}
+}
@@ -0,0 +1,197 @@
+package go_loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsStream pushes per-interval aggregates of a running workload to
+// browsers and other services as server-sent events, so a dashboard can plot
+// the run in real time. Pass it in Spec.Observers, mount it as an HTTP
+// handler, and Close it after the run.
+type MetricsStream struct {
+	interval time.Duration
+	// published counts the intervals sent so far. Samples are recorded in
+	// the slot of the current interval, so publishing never blocks them.
+	published   atomic.Uint64
+	slots       [2]streamSlot
+	phases      livePhases
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+	closed      bool
+	cancel      chan struct{}
+	done        chan struct{}
+	close       sync.Once
+}
+
+type streamSlot struct {
+	requests  atomic.Uint64
+	failed    atomic.Uint64
+	timedOut  atomic.Uint64
+	latencies histogram
+}
+
+// MetricsEvent is the data of one server-sent event: the results completed in
+// one interval. Latencies are in milliseconds.
+type MetricsEvent struct {
+	Time      time.Time `json:"time"`
+	Requests  uint64    `json:"requests"`
+	Failed    uint64    `json:"failed"`
+	TimedOut  uint64    `json:"timed_out"`
+	RPS       float64   `json:"rps"`
+	TargetRPS uint64    `json:"target_rps"`
+	P50       float64   `json:"p50_ms"`
+	P95       float64   `json:"p95_ms"`
+	P99       float64   `json:"p99_ms"`
+	// Phases names the phases that completed requests in the interval.
+	Phases []string `json:"phases"`
+}
+
+// NewMetricsStream creates a stream that sends an event every interval, one
+// second when interval is not positive.
+func NewMetricsStream(interval time.Duration) *MetricsStream {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s := &MetricsStream{
+		interval:    interval,
+		subscribers: make(map[chan []byte]struct{}),
+		cancel:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for i := range s.slots {
+		s.slots[i].latencies = newHistogram(defaultHistogramSubBits)
+	}
+	go s.run()
+	return s
+}
+
+// Observe implements Observer.
+func (s *MetricsStream) Observe(sample Sample) {
+	interval := s.published.Load()
+	slot := &s.slots[interval%2]
+	slot.requests.Add(1)
+	if sample.Failed {
+		slot.failed.Add(1)
+	}
+	if sample.TimedOut {
+		slot.timedOut.Add(1)
+	}
+	slot.latencies.record(sample.Latency)
+	s.phases.observe(sample.Phase, interval)
+}
+
+// ServeHTTP streams events to the client until it disconnects or the stream
+// is closed. Events are dropped for clients that fall behind.
+func (s *MetricsStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events := make(chan []byte, 16)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		http.Error(w, "metrics stream closed", http.StatusServiceUnavailable)
+		return
+	}
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, events)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Close stops the stream and ends every client's response.
+func (s *MetricsStream) Close() {
+	s.close.Do(func() {
+		close(s.cancel)
+		<-s.done
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		for events := range s.subscribers {
+			close(events)
+			delete(s.subscribers, events)
+		}
+	})
+}
+
+func (s *MetricsStream) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.cancel:
+			return
+		case now := <-ticker.C:
+			s.publish(now)
+		}
+	}
+}
+
+// publish starts the next interval and sends the finished one's aggregates
+// to every client. Samples that reach the finished slot late are sent with
+// its next use rather than lost.
+func (s *MetricsStream) publish(now time.Time) {
+	interval := s.published.Add(1) - 1
+	slot := &s.slots[interval%2]
+	var window latencies
+	window.take(&slot.latencies)
+	requests := slot.requests.Swap(0)
+	event := MetricsEvent{
+		Time:     now,
+		Requests: requests,
+		Failed:   slot.failed.Swap(0),
+		TimedOut: slot.timedOut.Swap(0),
+		RPS:      float64(requests) / s.interval.Seconds(),
+		P50:      float64(window.percentile(50)) / float64(time.Millisecond),
+		P95:      float64(window.percentile(95)) / float64(time.Millisecond),
+		P99:      float64(window.percentile(99)) / float64(time.Millisecond),
+		Phases:   []string{},
+	}
+	for _, info := range s.phases.since(interval) {
+		event.Phases = append(event.Phases, phaseLabel(info))
+		event.TargetRPS += info.RPS
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for events := range s.subscribers {
+		select {
+		case events <- data:
+		default:
+		}
+	}
+}
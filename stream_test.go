@@ -0,0 +1,79 @@
+package go_loadgen
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsStreamSendsServerSentEvents(t *testing.T) {
+	stream := NewMetricsStream(10 * time.Millisecond)
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("content type %q", contentType)
+	}
+	go func() {
+		for i := range 50 {
+			stream.Observe(Sample{Phase: PhaseInfo{Name: "steady", RPS: 100}, Latency: 2 * time.Millisecond, Failed: i%10 == 0})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	lines := bufio.NewScanner(response.Body)
+	var total, failed uint64
+	for total < 50 && lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event MetricsEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("event %q: %v", data, err)
+		}
+		if event.Requests != 0 && (event.TargetRPS != 100 || event.Phases[0] != "steady" || event.P50 < 1.9 || event.P50 > 2.1) {
+			t.Fatalf("event=%+v", event)
+		}
+		total, failed = total+event.Requests, failed+event.Failed
+	}
+	if total != 50 || failed != 5 {
+		t.Fatalf("streamed %d requests and %d failures, want every observed sample", total, failed)
+	}
+
+	stream.Close()
+	for lines.Scan() {
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatalf("stream did not end cleanly on Close: %v", err)
+	}
+}
+
+func TestMetricsStreamKeepsWorkloadsApart(t *testing.T) {
+	stream := NewMetricsStream(time.Hour)
+	defer stream.Close()
+	events := make(chan []byte, 1)
+	stream.mu.Lock()
+	stream.subscribers[events] = struct{}{}
+	stream.mu.Unlock()
+	stream.Observe(Sample{Phase: PhaseInfo{Workload: "api", Name: "steady", RPS: 100}})
+	stream.Observe(Sample{Phase: PhaseInfo{Workload: "batch", Name: "steady", RPS: 20}})
+	stream.publish(time.Now())
+
+	var event MetricsEvent
+	if err := json.Unmarshal(<-events, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Requests != 2 || event.TargetRPS != 120 || len(event.Phases) != 2 || event.Phases[0] != "api/steady" || event.Phases[1] != "batch/steady" {
+		t.Fatalf("event=%+v, want both workloads' phases", event)
+	}
+}
@@ -0,0 +1,288 @@
+package go_loadgen
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SummaryRecord is one result as read by a Summarizer.
+type SummaryRecord struct {
+	Phase string
+	// At is when the result completed. Throughput is measured between the
+	// first and last At of a phase.
+	At      time.Time
+	Latency time.Duration
+	// Error classifies a failed result, such as "timeout" or an HTTP status,
+	// and is empty for successes.
+	Error string
+}
+
+// Summary is the throughput, error breakdown, and latency percentiles of a
+// set of results, per phase and in total.
+type Summary struct {
+	// Phases are in the order their first result was added.
+	Phases []PhaseSummary
+	Total  PhaseSummary
+}
+
+// PhaseSummary summarizes the results of one phase.
+type PhaseSummary struct {
+	Phase    string
+	Requests uint64
+	Failed   uint64
+	// ErrorRate is the fraction of requests that failed.
+	ErrorRate float64
+	// Errors counts failed requests by SummaryRecord.Error.
+	Errors     map[string]uint64
+	Start, End time.Time
+	// Throughput is the completed requests per second between Start and End.
+	Throughput         float64
+	Mean, Max          time.Duration
+	P50, P90, P95, P99 time.Duration
+}
+
+// Summarizer accumulates results into a Summary. It replaces the analysis
+// script every load test otherwise needs: add records from memory, pass it in
+// Spec.Observers, or read a results file with SummarizeCSV. It is safe for
+// concurrent use, and percentiles are accurate to about 3%.
+type Summarizer struct {
+	mu     sync.Mutex
+	phases map[string]*phaseSummarizer
+	order  []string
+}
+
+type phaseSummarizer struct {
+	summary   PhaseSummary
+	nanos     uint64
+	latencies histogram
+}
+
+// NewSummarizer creates an empty summarizer.
+func NewSummarizer() *Summarizer {
+	return &Summarizer{phases: make(map[string]*phaseSummarizer)}
+}
+
+// Add records one result.
+func (s *Summarizer) Add(record SummaryRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phase, ok := s.phases[record.Phase]
+	if !ok {
+		phase = &phaseSummarizer{summary: PhaseSummary{Phase: record.Phase, Errors: map[string]uint64{}}, latencies: newHistogram(defaultHistogramSubBits)}
+		s.phases[record.Phase] = phase
+		s.order = append(s.order, record.Phase)
+	}
+	summary := &phase.summary
+	summary.Requests++
+	if record.Error != "" {
+		summary.Failed++
+		summary.Errors[record.Error]++
+	}
+	if !record.At.IsZero() {
+		if summary.Start.IsZero() || record.At.Before(summary.Start) {
+			summary.Start = record.At
+		}
+		if record.At.After(summary.End) {
+			summary.End = record.At
+		}
+	}
+	latency := max(record.Latency, 0)
+	phase.nanos += uint64(latency)
+	summary.Max = max(summary.Max, latency)
+	phase.latencies.record(latency)
+}
+
+// Observe implements Observer. Results are attributed to the phase's Name, or
+// to "phase N" for unnamed phases, and failures are classified as "timeout"
+// or "failed".
+func (s *Summarizer) Observe(sample Sample) {
+	record := SummaryRecord{Phase: sample.Phase.Name, At: time.Now(), Latency: sample.Latency}
+	if record.Phase == "" {
+		record.Phase = fmt.Sprintf("phase %d", sample.Phase.Index)
+	}
+	switch {
+	case sample.TimedOut:
+		record.Error = "timeout"
+	case sample.Failed:
+		record.Error = "failed"
+	}
+	s.Add(record)
+}
+
+// Summary returns the summary of the results added so far.
+func (s *Summarizer) Summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := Summary{Total: PhaseSummary{Phase: "total", Errors: map[string]uint64{}}}
+	var all latencies
+	var nanos uint64
+	for _, name := range s.order {
+		phase := s.phases[name]
+		var l latencies
+		l.add(&phase.latencies)
+		all.add(&phase.latencies)
+		nanos += phase.nanos
+		summary.Phases = append(summary.Phases, phase.summary.finish(phase.nanos, &l))
+
+		total := &summary.Total
+		total.Requests += phase.summary.Requests
+		total.Failed += phase.summary.Failed
+		for kind, count := range phase.summary.Errors {
+			total.Errors[kind] += count
+		}
+		if start := phase.summary.Start; !start.IsZero() && (total.Start.IsZero() || start.Before(total.Start)) {
+			total.Start = start
+		}
+		if phase.summary.End.After(total.End) {
+			total.End = phase.summary.End
+		}
+		total.Max = max(total.Max, phase.summary.Max)
+	}
+	summary.Total = summary.Total.finish(nanos, &all)
+	return summary
+}
+
+// finish derives the rates and percentiles of a summary from its counts.
+func (p PhaseSummary) finish(nanos uint64, l *latencies) PhaseSummary {
+	p.Errors = maps.Clone(p.Errors)
+	if p.Requests == 0 {
+		return p
+	}
+	p.ErrorRate = float64(p.Failed) / float64(p.Requests)
+	p.Mean = time.Duration(nanos / p.Requests)
+	if span := p.End.Sub(p.Start); span > 0 {
+		p.Throughput = float64(p.Requests) / span.Seconds()
+	}
+	// Bucket midpoints can overshoot the slowest request.
+	p.P50 = min(l.percentile(50), p.Max)
+	p.P90 = min(l.percentile(90), p.Max)
+	p.P95 = min(l.percentile(95), p.Max)
+	p.P99 = min(l.percentile(99), p.Max)
+	return p
+}
+
+// String formats the summary as a table with one row per phase and the total,
+// followed by the errors of each phase by count.
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %10s %8s %10s %10s %10s %10s %10s %10s\n", "phase", "requests", "errors", "rps", "mean", "p50", "p95", "p99", "max")
+	phases := append(slices.Clone(s.Phases), s.Total)
+	for _, p := range phases {
+		fmt.Fprintf(&b, "%-16s %10d %7.2f%% %10.1f %10s %10s %10s %10s %10s\n", p.Phase, p.Requests, 100*p.ErrorRate, p.Throughput,
+			p.Mean.Round(time.Microsecond), p.P50.Round(time.Microsecond), p.P95.Round(time.Microsecond), p.P99.Round(time.Microsecond), p.Max.Round(time.Microsecond))
+	}
+	for _, p := range s.Phases {
+		kinds := slices.SortedFunc(maps.Keys(p.Errors), func(a, b string) int {
+			return cmp.Or(cmp.Compare(p.Errors[b], p.Errors[a]), strings.Compare(a, b))
+		})
+		for _, kind := range kinds {
+			fmt.Fprintf(&b, "%-16s error %q: %d\n", p.Phase, kind, p.Errors[kind])
+		}
+	}
+	return b.String()
+}
+
+// SummaryColumns names the columns SummarizeCSV reads. Only Latency is
+// required; the zero value of the others matches the CSVCollector metadata
+// columns.
+type SummaryColumns struct {
+	// Phase defaults to "phase".
+	Phase string
+	// Timestamp defaults to "timestamp" and must be in RFC 3339 format.
+	Timestamp string
+	// Latency names the latency column, such as "latency_ms".
+	Latency string
+	// LatencyUnit is the unit of a numeric latency, one millisecond by
+	// default. Values with a unit suffix, such as "1.5ms", are parsed as
+	// durations.
+	LatencyUnit time.Duration
+	// Error names a column whose non-empty values are failures, classified by
+	// value.
+	Error string
+	// Failed names a boolean column marking failures, classified as "failed"
+	// unless Error has a value.
+	Failed string
+}
+
+// SummarizeCSV summarizes a results file with a header row, such as one
+// written by CSVCollector or StructCSVCollector. Missing optional columns are
+// ignored.
+func SummarizeCSV(r io.Reader, columns SummaryColumns) (Summary, error) {
+	if columns.Latency == "" {
+		return Summary{}, errors.New("summary needs a latency column")
+	}
+	if columns.Phase == "" {
+		columns.Phase = "phase"
+	}
+	if columns.Timestamp == "" {
+		columns.Timestamp = "timestamp"
+	}
+	if columns.LatencyUnit <= 0 {
+		columns.LatencyUnit = time.Millisecond
+	}
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	headers, err := reader.Read()
+	if err != nil {
+		return Summary{}, fmt.Errorf("read CSV header: %w", err)
+	}
+	index := func(name string) int {
+		if name == "" {
+			return -1
+		}
+		return slices.Index(headers, name)
+	}
+	phase, timestamp, latency, failure, failed := index(columns.Phase), index(columns.Timestamp), index(columns.Latency), index(columns.Error), index(columns.Failed)
+	if latency < 0 {
+		return Summary{}, fmt.Errorf("CSV has no %q column", columns.Latency)
+	}
+	summarizer := NewSummarizer()
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return summarizer.Summary(), nil
+		}
+		if err != nil {
+			return Summary{}, err
+		}
+		var result SummaryRecord
+		if result.Latency, err = parseSummaryLatency(record[latency], columns.LatencyUnit); err != nil {
+			return Summary{}, fmt.Errorf("line %d: latency: %w", line, err)
+		}
+		if phase >= 0 {
+			result.Phase = record[phase]
+		}
+		if timestamp >= 0 && record[timestamp] != "" {
+			if result.At, err = time.Parse(time.RFC3339Nano, record[timestamp]); err != nil {
+				return Summary{}, fmt.Errorf("line %d: timestamp: %w", line, err)
+			}
+		}
+		if failure >= 0 {
+			result.Error = record[failure]
+		}
+		if failed >= 0 && result.Error == "" {
+			if ok, err := strconv.ParseBool(record[failed]); err != nil {
+				return Summary{}, fmt.Errorf("line %d: failed: %w", line, err)
+			} else if ok {
+				result.Error = "failed"
+			}
+		}
+		summarizer.Add(result)
+	}
+}
+
+func parseSummaryLatency(value string, unit time.Duration) (time.Duration, error) {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(number * float64(unit)), nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,77 @@
+package go_loadgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizerBreaksDownPhases(t *testing.T) {
+	summarizer := NewSummarizer()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 100 {
+		record := SummaryRecord{Phase: "steady", At: start.Add(time.Duration(i) * 10 * time.Millisecond), Latency: time.Duration(i+1) * time.Millisecond}
+		switch {
+		case i%10 == 0:
+			record.Error = "503"
+		case i%25 == 1:
+			record.Error = "timeout"
+		}
+		summarizer.Add(record)
+	}
+	summarizer.Add(SummaryRecord{Phase: "spike", At: start, Latency: time.Second})
+
+	summary := summarizer.Summary()
+	if len(summary.Phases) != 2 || summary.Phases[0].Phase != "steady" || summary.Total.Requests != 101 {
+		t.Fatalf("phases=%+v total=%d", summary.Phases, summary.Total.Requests)
+	}
+	steady := summary.Phases[0]
+	if steady.Failed != 14 || steady.Errors["503"] != 10 || steady.Errors["timeout"] != 4 || steady.Max != 100*time.Millisecond {
+		t.Fatalf("failed=%d errors=%v max=%s", steady.Failed, steady.Errors, steady.Max)
+	}
+	if steady.Throughput < 100 || steady.Throughput > 102 {
+		t.Fatalf("throughput=%.1f, want 100 requests over 0.99s", steady.Throughput)
+	}
+	if steady.P50 < 48*time.Millisecond || steady.P50 > 52*time.Millisecond || steady.P99 < 96*time.Millisecond {
+		t.Fatalf("p50=%s p99=%s", steady.P50, steady.P99)
+	}
+	if summary.Total.Max != time.Second || summary.Total.Errors["503"] != 10 {
+		t.Fatalf("total=%+v", summary.Total)
+	}
+	if text := summary.String(); !strings.Contains(text, "steady") || !strings.Contains(text, `error "503": 10`) || !strings.Contains(text, "total") {
+		t.Fatalf("summary text:\n%s", text)
+	}
+}
+
+func TestSummarizeCSVReadsCollectorColumns(t *testing.T) {
+	file := "timestamp,workload,phase,worker,status,latency_ms,error\n" +
+		"2024-01-01T00:00:00Z,checkout,warm,,200,1.5,\n" +
+		"2024-01-01T00:00:01Z,checkout,warm,,500,20,\n" +
+		"2024-01-01T00:00:02Z,checkout,warm,,0,3ms,reset\n"
+	summary, err := SummarizeCSV(strings.NewReader(file), SummaryColumns{Latency: "latency_ms", Error: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warm := summary.Phases[0]
+	if len(summary.Phases) != 1 || warm.Requests != 3 || warm.Failed != 1 || warm.Errors["reset"] != 1 || warm.Max != 20*time.Millisecond || warm.Throughput != 1.5 {
+		t.Fatalf("summary=%+v", summary)
+	}
+	if _, err := SummarizeCSV(strings.NewReader(file), SummaryColumns{Latency: "duration"}); err == nil {
+		t.Fatal("expected a missing latency column to be rejected")
+	}
+}
+
+func TestSummarizerObservesWorkload(t *testing.T) {
+	summarizer := NewSummarizer()
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, testClient(func(context.Context, testRequest) testResult { return testResult{} }), testProvider{}, &testCollector{})},
+		Observers: []Observer{summarizer},
+		Phases:    []Phase{{Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	if summary := summarizer.Summary(); len(summary.Phases) != 1 || summary.Phases[0].Phase != "phase 0" || summary.Total.Requests != report.Completed {
+		t.Fatalf("summary=%+v completed=%d", summary, report.Completed)
+	}
+}
@@ -0,0 +1,292 @@
+package go_loadgen
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TDigestCollector summarizes request latencies in constant memory for
+// multi-hour soak tests. Every window it writes a CSV row with the window's
+// request and failure counts and latency quantiles, and Quantile reads the
+// whole run's. Pass it in Spec.Observers and Close it after the run.
+type TDigestCollector struct {
+	mu        sync.Mutex
+	window    tdigest
+	total     tdigest
+	requests  uint64
+	failed    uint64
+	started   time.Time
+	quantiles []float64
+	file      *os.File
+	writer    *csv.Writer
+	err       error
+	cancel    chan struct{}
+	done      chan struct{}
+	close     sync.Once
+}
+
+// TDigestCollectorOption configures a TDigestCollector.
+type TDigestCollectorOption func(*tdigestCollectorConfig)
+
+type tdigestCollectorConfig struct {
+	compression float64
+	quantiles   []float64
+}
+
+// WithTDigestQuantiles sets the quantiles written per window, between 0 and
+// 1. The default is 0.5, 0.95, and 0.99.
+func WithTDigestQuantiles(quantiles ...float64) TDigestCollectorOption {
+	return func(cfg *tdigestCollectorConfig) {
+		cfg.quantiles = quantiles
+	}
+}
+
+// WithTDigestCompression trades memory for accuracy. The default of 100 keeps
+// a few hundred centroids per digest.
+func WithTDigestCompression(compression float64) TDigestCollectorOption {
+	return func(cfg *tdigestCollectorConfig) {
+		if compression >= 20 {
+			cfg.compression = compression
+		}
+	}
+}
+
+// NewTDigestCollector creates a collector that writes a row to filePath every window.
+func NewTDigestCollector(filePath string, window time.Duration, opts ...TDigestCollectorOption) (*TDigestCollector, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	cfg := tdigestCollectorConfig{compression: 100, quantiles: []float64{0.5, 0.95, 0.99}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	for _, q := range cfg.quantiles {
+		if q < 0 || q > 1 {
+			return nil, errors.New("quantiles must be between 0 and 1")
+		}
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &TDigestCollector{
+		window:    newTDigest(cfg.compression),
+		total:     newTDigest(cfg.compression),
+		started:   time.Now(),
+		quantiles: slices.Clone(cfg.quantiles),
+		file:      file,
+		writer:    csv.NewWriter(file),
+		cancel:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	header := []string{"window_start", "window_end", "requests", "failed"}
+	for _, q := range c.quantiles {
+		header = append(header, "p"+strconv.FormatFloat(q*100, 'f', -1, 64)+"_ms")
+	}
+	c.write(header)
+	go c.run(window)
+
+	return c, nil
+}
+
+// Observe implements Observer.
+func (c *TDigestCollector) Observe(sample Sample) {
+	latency := float64(sample.Latency) / float64(time.Millisecond)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.window.add(latency)
+	c.total.add(latency)
+	c.requests++
+	if sample.Failed {
+		c.failed++
+	}
+}
+
+// Quantile returns the approximate latency at quantile q, between 0 and 1,
+// over everything observed so far.
+func (c *TDigestCollector) Quantile(q float64) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.total.quantile(q) * float64(time.Millisecond))
+}
+
+// Close writes the final partial window and closes the file. Errors are
+// reported by Err.
+func (c *TDigestCollector) Close() {
+	c.close.Do(func() {
+		close(c.cancel)
+		<-c.done
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.rotate(time.Now())
+		c.writer.Flush()
+		c.setErr(c.writer.Error())
+		c.setErr(c.file.Close())
+	})
+}
+
+// CloseAndErr closes the collector and returns the first write error observed
+// by the collector.
+func (c *TDigestCollector) CloseAndErr() error {
+	c.Close()
+	return c.Err()
+}
+
+// Err returns the first write error observed by the collector.
+func (c *TDigestCollector) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *TDigestCollector) run(window time.Duration) {
+	defer close(c.done)
+	t := time.NewTicker(window)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.cancel:
+			return
+		case now := <-t.C:
+			c.mu.Lock()
+			c.rotate(now)
+			c.writer.Flush()
+			c.setErr(c.writer.Error())
+			c.mu.Unlock()
+		}
+	}
+}
+
+// rotate writes the current window's row and starts a new window. c.mu must be held.
+func (c *TDigestCollector) rotate(now time.Time) {
+	row := []string{c.started.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), strconv.FormatUint(c.requests, 10), strconv.FormatUint(c.failed, 10)}
+	for _, q := range c.quantiles {
+		value := ""
+		if c.requests != 0 {
+			value = strconv.FormatFloat(c.window.quantile(q), 'f', 3, 64)
+		}
+		row = append(row, value)
+	}
+	c.write(row)
+	c.window.reset()
+	c.requests, c.failed, c.started = 0, 0, now
+}
+
+func (c *TDigestCollector) write(row []string) {
+	c.setErr(c.writer.Write(row))
+}
+
+func (c *TDigestCollector) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// tdigest is a merging t-digest: an approximate quantile sketch whose memory
+// is bounded by its compression, however many values it summarizes. Accuracy
+// is highest at the tails, where percentiles such as p99 are read.
+type tdigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min, max    float64
+}
+
+type centroid struct {
+	mean, weight float64
+}
+
+func newTDigest(compression float64) tdigest {
+	return tdigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, 5*int(compression)),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (d *tdigest) add(value float64) {
+	d.buffer = append(d.buffer, centroid{mean: value, weight: 1})
+	d.count++
+	d.min, d.max = min(d.min, value), max(d.max, value)
+	if len(d.buffer) == cap(d.buffer) {
+		d.merge()
+	}
+}
+
+func (d *tdigest) reset() {
+	d.centroids, d.buffer, d.count = d.centroids[:0], d.buffer[:0], 0
+	d.min, d.max = math.Inf(1), math.Inf(-1)
+}
+
+// k is the k1 scale function, which keeps centroids small near the tails.
+func (d *tdigest) k(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (d *tdigest) kInverse(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
+
+// merge folds buffered values into the centroids.
+func (d *tdigest) merge() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.centroids, d.buffer...)
+	slices.SortFunc(all, func(a, b centroid) int { return cmp.Compare(a.mean, b.mean) })
+	merged := all[:1]
+	var soFar float64
+	limit := d.count * d.kInverse(d.k(0)+1)
+	for _, next := range all[1:] {
+		current := &merged[len(merged)-1]
+		if soFar+current.weight+next.weight <= limit {
+			current.mean += (next.mean - current.mean) * next.weight / (current.weight + next.weight)
+			current.weight += next.weight
+			continue
+		}
+		soFar += current.weight
+		limit = d.count * d.kInverse(d.k(soFar/d.count)+1)
+		merged = append(merged, next)
+	}
+	d.centroids = merged
+	d.buffer = d.buffer[:0]
+}
+
+// quantile returns the approximate value at quantile q, between 0 and 1.
+func (d *tdigest) quantile(q float64) float64 {
+	d.merge()
+	if d.count == 0 {
+		return 0
+	}
+	target := min(max(q, 0), 1) * d.count
+	var cumulative float64
+	for i, c := range d.centroids {
+		center := cumulative + c.weight/2
+		if target < center {
+			if i == 0 {
+				return d.min + (c.mean-d.min)*target/center
+			}
+			previous := d.centroids[i-1]
+			previousCenter := cumulative - previous.weight/2
+			return previous.mean + (c.mean-previous.mean)*(target-previousCenter)/(center-previousCenter)
+		}
+		cumulative += c.weight
+	}
+	last := d.centroids[len(d.centroids)-1]
+	lastCenter := d.count - last.weight/2
+	if d.count == lastCenter {
+		return d.max
+	}
+	return last.mean + (d.max-last.mean)*(target-lastCenter)/(d.count-lastCenter)
+}
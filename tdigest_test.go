@@ -0,0 +1,66 @@
+package go_loadgen
+
+import (
+	"encoding/csv"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTDigestQuantilesAreAccurateInBoundedMemory(t *testing.T) {
+	digest := newTDigest(100)
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 1_000_000 {
+		digest.add(random.Float64() * 1000)
+	}
+	for _, q := range []float64{0.5, 0.95, 0.99, 0.999} {
+		if got := digest.quantile(q); math.Abs(got-q*1000) > 5 {
+			t.Errorf("q%g=%.2f, want %.2f within 0.5%%", q, got, q*1000)
+		}
+	}
+	if len(digest.centroids) > 200 {
+		t.Fatalf("%d centroids, want memory bounded by the compression", len(digest.centroids))
+	}
+}
+
+func TestTDigestCollectorWritesWindowRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "windows.csv")
+	collector, err := NewTDigestCollector(path, 20*time.Millisecond, WithTDigestQuantiles(0.5, 0.999))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 1000; i++ {
+		collector.Observe(Sample{Latency: time.Duration(i) * time.Microsecond, Failed: i%10 == 0})
+	}
+	time.Sleep(30 * time.Millisecond)
+	collector.Observe(Sample{Latency: time.Millisecond})
+	if err := collector.CloseAndErr(); err != nil {
+		t.Fatal(err)
+	}
+	if p50 := collector.Quantile(0.5); p50 < 490*time.Microsecond || p50 > 510*time.Microsecond {
+		t.Fatalf("p50=%s over the run, want about 500µs", p50)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 3 || rows[0][4] != "p50_ms" || rows[0][5] != "p99.9_ms" {
+		t.Fatalf("rows=%q, want a header and at least two windows", rows)
+	}
+	if rows[1][2] != "1000" || rows[1][3] != "100" || rows[len(rows)-1][2] != "1" {
+		t.Fatalf("rows=%q, want each window's own counts", rows)
+	}
+	if p50, _ := strconv.ParseFloat(rows[1][4], 64); p50 < 0.49 || p50 > 0.51 {
+		t.Fatalf("p50=%s ms, want about 0.5", rows[1][4])
+	}
+}
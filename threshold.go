@@ -0,0 +1,117 @@
+package go_loadgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Threshold is a pass/fail criterion evaluated over a run's results when it
+// ends, for use as a CI gate. Zero fields disable their limit; warm-up results
+// are not evaluated.
+type Threshold struct {
+	// MaxLatency is the tolerated response time at Percentile, measured around
+	// each client call. Zero Percentile uses 99.
+	MaxLatency time.Duration
+	Percentile float64
+	// MaxErrorRate is the tolerated fraction of failed results.
+	MaxErrorRate float64
+	// MinThroughput is the required rate of completed requests per second of
+	// scheduling, not counting time paused with Pause.
+	MinThroughput float64
+}
+
+// String describes the threshold's limits, such as
+// "p99 <= 200ms, error rate <= 0.01".
+func (t Threshold) String() string {
+	var limits []string
+	if t.MaxLatency > 0 {
+		limits = append(limits, fmt.Sprintf("p%s <= %s", strconv.FormatFloat(t.percentile(), 'f', -1, 64), t.MaxLatency))
+	}
+	if t.MaxErrorRate > 0 {
+		limits = append(limits, fmt.Sprintf("error rate <= %s", strconv.FormatFloat(t.MaxErrorRate, 'f', -1, 64)))
+	}
+	if t.MinThroughput > 0 {
+		limits = append(limits, fmt.Sprintf("throughput >= %s rps", strconv.FormatFloat(t.MinThroughput, 'f', -1, 64)))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// percentile returns the percentile MaxLatency applies to.
+func (t Threshold) percentile() float64 {
+	if t.Percentile == 0 {
+		return 99
+	}
+	return t.Percentile
+}
+
+func (t Threshold) validate() error {
+	if t.MaxLatency < 0 || t.MaxErrorRate < 0 || t.MaxErrorRate > 1 || t.MinThroughput < 0 || t.Percentile < 0 || t.Percentile >= 100 {
+		return errors.New("threshold needs non-negative limits, an error rate up to one, and a percentile below 100")
+	}
+	if t.MaxLatency == 0 && t.MaxErrorRate == 0 && t.MinThroughput == 0 {
+		return errors.New("threshold must set a limit")
+	}
+	return nil
+}
+
+// ThresholdResult is a threshold with the values observed for it.
+type ThresholdResult struct {
+	Threshold
+	Latency    time.Duration
+	ErrorRate  float64
+	Throughput float64
+	Passed     bool
+}
+
+// Passed reports whether every threshold passed.
+func (r Report) Passed() bool {
+	for _, result := range r.Thresholds {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// thresholdResults tracks the measured results thresholds are evaluated over.
+type thresholdResults struct {
+	completed atomic.Uint64
+	failed    atomic.Uint64
+	latencies histogram
+}
+
+func (t *thresholdResults) record(done completion) {
+	t.completed.Add(1)
+	if done.failed {
+		t.failed.Add(1)
+	}
+	t.latencies.record(done.latency)
+}
+
+func (t *thresholdResults) evaluate(thresholds []Threshold, scheduling time.Duration) []ThresholdResult {
+	var snapshot latencies
+	snapshot.add(&t.latencies)
+	completed, failed := t.completed.Load(), t.failed.Load()
+	results := make([]ThresholdResult, len(thresholds))
+	for i, threshold := range thresholds {
+		result := ThresholdResult{Threshold: threshold, Passed: true}
+		result.Latency = snapshot.percentile(threshold.percentile())
+		if completed != 0 {
+			result.ErrorRate = float64(failed) / float64(completed)
+		}
+		if scheduling > 0 {
+			result.Throughput = float64(completed) / scheduling.Seconds()
+		}
+		if (threshold.MaxLatency > 0 && result.Latency > threshold.MaxLatency) ||
+			(threshold.MaxErrorRate > 0 && result.ErrorRate > threshold.MaxErrorRate) ||
+			(threshold.MinThroughput > 0 && result.Throughput < threshold.MinThroughput) {
+			result.Passed = false
+		}
+		results[i] = result
+	}
+	return results
+}
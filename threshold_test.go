@@ -0,0 +1,72 @@
+package go_loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThresholdsGateOnResults(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(2 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:    50 * time.Millisecond,
+		MaxInFlight: 1,
+		Thresholds: []Threshold{
+			{MaxLatency: time.Second, MaxErrorRate: 0.01},
+			{MaxLatency: time.Millisecond, Percentile: 50},
+			{MinThroughput: 1000},
+		},
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if len(report.Thresholds) != 3 || report.Passed() {
+		t.Fatalf("thresholds=%+v, want results and a failed gate", report.Thresholds)
+	}
+	healthy, slow, throughput := report.Thresholds[0], report.Thresholds[1], report.Thresholds[2]
+	if !healthy.Passed || healthy.ErrorRate != 0 {
+		t.Fatalf("healthy=%+v, want pass", healthy)
+	}
+	if slow.Passed || slow.Latency < time.Millisecond {
+		t.Fatalf("slow=%+v, want median latency above 1ms to fail", slow)
+	}
+	if throughput.Passed || throughput.Throughput == 0 || throughput.Throughput >= 1000 {
+		t.Fatalf("throughput=%+v, want one-at-a-time throughput below 1000/s to fail", throughput)
+	}
+}
+
+func TestThroughputThresholdExcludesPausedTime(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	workload := mustWorkload(t, Spec{
+		Duration:   time.Second,
+		Clock:      clock,
+		Thresholds: []Threshold{{MinThroughput: 50}},
+		Endpoints:  map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:     []Phase{{Duration: time.Second, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	ctx, finished := context.WithCancel(context.Background())
+	var report Report
+	go func() {
+		defer finished()
+		report = mustRun(t, workload)
+	}()
+	paused := false
+	for clock.BlockUntilContext(ctx, 1) == nil {
+		if !paused && clock.Now().Sub(time.Unix(0, 0)) >= 500*time.Millisecond {
+			paused = true
+			workload.Pause()
+			clock.Advance(10 * time.Second)
+			workload.Resume()
+			continue
+		}
+		clock.Advance(10 * time.Millisecond)
+	}
+	if report.Paused != 10*time.Second || !report.Passed() {
+		t.Fatalf("paused=%s thresholds=%+v, want throughput measured without the pause", report.Paused, report.Thresholds)
+	}
+}
@@ -0,0 +1,114 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeClientCertificate writes a self-signed client certificate and key and
+// returns their paths with the parsed certificate.
+func writeClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "loadgen"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, certificate
+}
+
+func TestLoadConnectsToMutualTLSServer(t *testing.T) {
+	certFile, keyFile, clientCertificate := writeClientCertificate(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	clients := x509.NewCertPool()
+	clients.AddCert(clientCertificate)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	server.StartTLS()
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(options Options) (string, error) {
+		config, err := Load(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		defer client.CloseIdleConnections()
+		response, err := client.Get(server.URL)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		return string(body), err
+	}
+	if body, err := get(Options{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"}); err != nil || body != "loadgen" {
+		t.Fatalf("body=%q err=%v, want the client certificate accepted", body, err)
+	}
+	if _, err := get(Options{CAFile: caFile, ServerName: "example.com"}); err == nil {
+		t.Fatal("expected the server to require a client certificate")
+	}
+	if _, err := get(Options{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "wrong.test"}); err == nil || !strings.Contains(err.Error(), "wrong.test") {
+		t.Fatalf("err=%v, want the overridden name verified", err)
+	}
+	if _, err := get(Options{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile}); err != nil {
+		t.Fatalf("err=%v, want verification skipped", err)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range []Options{{CAFile: empty}, {CAFile: empty + ".missing"}, {CertFile: empty}, {CertFile: empty, KeyFile: empty}} {
+		if _, err := Load(options); err == nil {
+			t.Fatalf("options=%+v, want an error", options)
+		}
+	}
+}
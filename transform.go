@@ -0,0 +1,150 @@
+package go_loadgen
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// ScaleTime stretches a schedule's timeline by factor, or compresses it for
+// factors below one: start offsets, durations, ramp intervals, warm-ups, and
+// trace offsets are multiplied, while rates are kept, so the shape of the
+// offered rate over time is preserved. Traces keep their arrival count and so
+// change rate by the inverse of factor. The input is not modified. Factor
+// must be positive and finite.
+func ScaleTime(phases []Phase, factor float64) ([]Phase, error) {
+	if err := validateFactor(factor); err != nil {
+		return nil, err
+	}
+	scale := func(d time.Duration) time.Duration { return time.Duration(float64(d) * factor) }
+	scaled := make([]Phase, len(phases))
+	for i, phase := range phases {
+		phase.StartAt = scale(phase.StartAt)
+		phase.Duration = max(scale(phase.Duration), 1)
+		phase.WarmUp = scale(phase.WarmUp)
+		if phase.Ramp != nil {
+			ramp := *phase.Ramp
+			ramp.Every = max(scale(ramp.Every), 1)
+			phase.Ramp = &ramp
+		}
+		if phase.Trace != nil {
+			trace := make([]time.Duration, len(phase.Trace))
+			for j, offset := range phase.Trace {
+				trace[j] = min(scale(offset), phase.Duration)
+			}
+			phase.Trace = trace
+		}
+		scaled[i] = phase
+	}
+	return scaled, nil
+}
+
+// FitDuration compresses or stretches a profile expressed in real time, such
+// as a week of traffic, so that it ends at duration, and returns the scale
+// factor applied. See ScaleTime.
+func FitDuration(phases []Phase, duration time.Duration) ([]Phase, float64, error) {
+	if duration <= 0 {
+		return nil, 0, errors.New("fit duration must be positive")
+	}
+	var end time.Duration
+	for _, phase := range phases {
+		end = max(end, phase.StartAt+phase.Duration)
+	}
+	if end == 0 {
+		scaled, err := ScaleTime(phases, 1)
+		return scaled, 1, err
+	}
+	factor := float64(duration) / float64(end)
+	scaled, err := ScaleTime(phases, factor)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range scaled {
+		// Rounding must not push a phase past the new end.
+		scaled[i].Duration = min(scaled[i].Duration, duration-scaled[i].StartAt)
+		for j, offset := range scaled[i].Trace {
+			scaled[i].Trace[j] = min(offset, scaled[i].Duration)
+		}
+	}
+	return scaled, factor, nil
+}
+
+// ScaleRates multiplies the offered load of every phase by factor: rates,
+// ramp targets and steps, and burst sizes, rounded and kept at least one.
+// Traces, custom pacers, and unpaced users are unchanged. The input is not
+// modified. Factor must be positive and finite.
+func ScaleRates(phases []Phase, factor float64) ([]Phase, error) {
+	if err := validateFactor(factor); err != nil {
+		return nil, err
+	}
+	scale := func(value uint64) uint64 {
+		if value == 0 {
+			return 0
+		}
+		return max(uint64(math.Round(float64(value)*factor)), 1)
+	}
+	scaled := slices.Clone(phases)
+	for i := range scaled {
+		phase := &scaled[i]
+		phase.RPS = scale(phase.RPS)
+		if phase.Ramp != nil {
+			ramp := *phase.Ramp
+			ramp.To, ramp.Step = scale(ramp.To), scale(ramp.Step)
+			phase.Ramp = &ramp
+		}
+		if phase.Burst != nil {
+			burst := *phase.Burst
+			burst.Size = scale(burst.Size)
+			phase.Burst = &burst
+		}
+	}
+	return scaled, nil
+}
+
+func validateFactor(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 1) {
+		return fmt.Errorf("scale factor %v must be positive and finite", factor)
+	}
+	return nil
+}
+
+// ShufflePhases reorders phases by a permutation drawn from seed and lays them
+// out back to back from the earliest start, as Sequence does, so gaps between
+// the original phases are not kept. The same seed gives the same order.
+func ShufflePhases(phases []Phase, seed uint64) []Phase {
+	if len(phases) == 0 {
+		return nil
+	}
+	shuffled := slices.Clone(phases)
+	random := phaseRandom{state: splitMix64(seed)}
+	for i := len(shuffled) - 1; i > 0; i-- {
+		j := int(random.next() % uint64(i+1))
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	start := slices.MinFunc(phases, func(a, b Phase) int { return cmp.Compare(a.StartAt, b.StartAt) }).StartAt
+	sequence := Sequence(0, shuffled...)
+	for i := range sequence {
+		sequence[i].StartAt += start
+	}
+	return sequence
+}
+
+// DropPhases replaces each phase with an idle one of the same name, tags, and
+// timing with the given probability, drawn from seed, so a variant keeps the
+// original timeline with some load removed. Probability must be within [0, 1].
+func DropPhases(phases []Phase, probability float64, seed uint64) ([]Phase, error) {
+	if !(probability >= 0 && probability <= 1) {
+		return nil, fmt.Errorf("drop probability %v must be within [0, 1]", probability)
+	}
+	dropped := slices.Clone(phases)
+	random := phaseRandom{state: splitMix64(seed)}
+	for i, phase := range dropped {
+		if float64(random.next()>>11)/(1<<53) < probability {
+			dropped[i] = Phase{Name: phase.Name, Tags: phase.Tags, Idle: true, StartAt: phase.StartAt, Duration: phase.Duration}
+		}
+	}
+	return dropped, nil
+}
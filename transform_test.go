@@ -0,0 +1,128 @@
+package go_loadgen
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFitDurationCompressesAWeek(t *testing.T) {
+	day := 24 * time.Hour
+	targets := []Target{{Endpoint: "one", Weight: 1}}
+	week := []Phase{
+		{Duration: 5 * day, RPS: 100, Ramp: &Ramp{To: 400, Step: 100, Every: day}, Targets: targets},
+		{StartAt: 5 * day, Duration: 2 * day, Trace: []time.Duration{0, day, 2 * day}, Targets: targets},
+	}
+	phases, factor, err := FitDuration(week, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factor != 1.0/168 {
+		t.Fatalf("factor=%v, want 1/168", factor)
+	}
+	if week[0].Duration != 5*day || week[0].Ramp.Every != day || week[1].Trace[1] != day {
+		t.Fatal("input phases were modified")
+	}
+	ramp, trace := phases[0], phases[1]
+	if ramp.Duration != 5*time.Hour/7 || ramp.Ramp.Every != time.Hour/7 || ramp.RPS != 100 || ramp.Ramp.To != 400 {
+		t.Fatalf("ramp=%+v ramp=%+v, want its timeline compressed and rates kept", ramp, *ramp.Ramp)
+	}
+	if trace.StartAt != 5*time.Hour/7 || trace.Duration != 2*time.Hour/7 || trace.Trace[2] != trace.Duration {
+		t.Fatalf("trace phase=%+v, want its offsets compressed", trace)
+	}
+	mustWorkload(t, Spec{
+		Duration:  time.Hour,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    phases,
+	})
+	if _, _, err := FitDuration(week, 0); err == nil {
+		t.Fatal("expected a zero duration to be rejected")
+	}
+}
+
+func TestMutationsDeriveSeededVariants(t *testing.T) {
+	targets := []Target{{Endpoint: "one", Weight: 1}}
+	phases := []Phase{
+		{Name: "a", StartAt: time.Second, Duration: time.Second, RPS: 100, Ramp: &Ramp{To: 300, Step: 50, Every: 100 * time.Millisecond}, Targets: targets},
+		{Name: "b", StartAt: 2 * time.Second, Duration: 2 * time.Second, Burst: &Burst{Size: 3, Every: time.Second}, Targets: targets},
+		{Name: "c", StartAt: 4 * time.Second, Duration: 3 * time.Second, RPS: 1, Targets: targets},
+		{Name: "d", StartAt: 7 * time.Second, Duration: 4 * time.Second, RPS: 10, Targets: targets},
+	}
+
+	scaled, err := ScaleRates(phases, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := scaled[0]; a.RPS != 50 || a.Ramp.To != 150 || a.Ramp.Step != 25 || scaled[1].Burst.Size != 2 || scaled[2].RPS != 1 {
+		t.Fatalf("scaled=%+v, want rates halved and kept positive", scaled)
+	}
+	if phases[0].RPS != 100 || phases[0].Ramp.To != 300 || phases[1].Burst.Size != 3 {
+		t.Fatal("input phases were modified")
+	}
+
+	shuffled := ShufflePhases(phases, 42)
+	names := func(phases []Phase) []string {
+		var names []string
+		for _, phase := range phases {
+			names = append(names, phase.Name)
+		}
+		return names
+	}
+	if got := names(shuffled); slices.Equal(got, []string{"a", "b", "c", "d"}) || !slices.Equal(got, names(ShufflePhases(phases, 42))) {
+		t.Fatalf("order=%v, want a reproducible permutation", got)
+	}
+	at := time.Second
+	for _, phase := range shuffled {
+		if phase.StartAt != at {
+			t.Fatalf("shuffled=%+v, want phases back to back from the first start", shuffled)
+		}
+		at += phase.Duration
+	}
+
+	dropped, err := DropPhases(phases, 0.5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idle int
+	for i, phase := range dropped {
+		if phase.Idle {
+			idle++
+			if phase.Name != phases[i].Name || phase.StartAt != phases[i].StartAt || phase.RPS != 0 {
+				t.Fatalf("dropped phase=%+v, want an idle phase on the same timeline", phase)
+			}
+		}
+	}
+	if idle == 0 || idle == len(phases) {
+		t.Fatalf("dropped=%+v, want some phases dropped", dropped)
+	}
+	all, err := DropPhases(phases, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range all {
+		if !phase.Idle {
+			t.Fatal("expected every phase dropped with probability one")
+		}
+	}
+	for _, variant := range [][]Phase{scaled, shuffled, dropped} {
+		mustWorkload(t, Spec{Duration: 11 * time.Second, Endpoints: map[string]Endpoint{"one": &countingEndpoint{}}, Phases: variant})
+	}
+}
+
+func TestTransformsRejectInvalidFactors(t *testing.T) {
+	phases := []Phase{{Duration: time.Second, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}}
+	for _, factor := range []float64{0, -1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := ScaleRates(phases, factor); err == nil {
+			t.Fatalf("expected rate factor %v to be rejected", factor)
+		}
+		if _, err := ScaleTime(phases, factor); err == nil {
+			t.Fatalf("expected time factor %v to be rejected", factor)
+		}
+	}
+	for _, probability := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := DropPhases(phases, probability, 1); err == nil {
+			t.Fatalf("expected probability %v to be rejected", probability)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Uploader ships a finished result file to durable storage, so results
+// survive the teardown of ephemeral load generator machines.
+type Uploader interface {
+	Upload(ctx context.Context, path string) error
+}
+
+// UploaderFunc adapts a function to an Uploader.
+type UploaderFunc func(ctx context.Context, path string) error
+
+// Upload implements Uploader.
+func (f UploaderFunc) Upload(ctx context.Context, path string) error { return f(ctx, path) }
+
+// HTTPUploader PUTs each file to the URL returned for its base name. Presigned
+// S3 and GCS URLs and Azure Blob SAS URLs accept such uploads without a cloud
+// SDK; Azure additionally needs the x-ms-blob-type: BlockBlob header.
+type HTTPUploader struct {
+	URL func(name string) (string, error)
+	// Header is added to every upload.
+	Header http.Header
+	// Client sends uploads. Nil uses http.DefaultClient.
+	Client *http.Client
+}
+
+// Upload implements Uploader.
+func (u HTTPUploader) Upload(ctx context.Context, path string) error {
+	if u.URL == nil {
+		return errors.New("http uploader needs a URL function")
+	}
+	url, err := u.URL(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, url, file)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = info.Size()
+	for key, values := range u.Header {
+		request.Header[key] = values
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("upload %s: %s", filepath.Base(path), response.Status)
+	}
+	return nil
+}
+
+// upload ships path and, once it is stored, optionally removes the local copy.
+func upload(uploader Uploader, path string, removeLocal bool) error {
+	if err := uploader.Upload(context.Background(), path); err != nil {
+		return fmt.Errorf("upload %s: %w", path, err)
+	}
+	if removeLocal {
+		return os.Remove(path)
+	}
+	return nil
+}
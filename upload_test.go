@@ -0,0 +1,98 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCSVCollectorUploadsFinishedFiles(t *testing.T) {
+	var mu sync.Mutex
+	var uploaded []string
+	uploader := UploaderFunc(func(_ context.Context, path string) error {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		uploaded = append(uploaded, path)
+		return nil
+	})
+	collector, err := NewCSVCollector[testCSVData](filepath.Join(t.TempDir(), "results.csv"), time.Hour, WithCSVCollectorRotation(40, 0), WithCSVCollectorUpload(uploader, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 4 {
+		collector.Collect(testCSVData{ID: i, Message: "upload", Value: 1})
+	}
+	collector.Close()
+
+	files := collector.Files()
+	slices.Sort(uploaded)
+	if len(files) != 2 || !slices.Equal(uploaded, files) {
+		t.Fatalf("uploaded=%q files=%q, want every finished file uploaded", uploaded, files)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("stat %s: %v, want the local copy removed", file, err)
+		}
+	}
+}
+
+func TestGobCollectorReportsUploadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.gob")
+	collector, err := NewGobCollector[testCSVData](path, time.Hour, WithGobCollectorUpload(UploaderFunc(func(context.Context, string) error {
+		return errors.New("bucket unavailable")
+	}), true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collect(testCSVData{ID: 1})
+	if err := collector.CloseAndErr(); err == nil {
+		t.Fatal("expected the failed upload to be reported")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("stat: %v, want the local copy kept after a failed upload", err)
+	}
+}
+
+func TestHTTPUploaderPutsFile(t *testing.T) {
+	var body, blobType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/bucket/results.csv" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		content, _ := io.ReadAll(r.Body)
+		body, blobType = string(content), r.Header.Get("x-ms-blob-type")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := os.WriteFile(path, []byte("id\n1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uploader := HTTPUploader{
+		URL:    func(name string) (string, error) { return server.URL + "/bucket/" + name, nil },
+		Header: http.Header{"X-Ms-Blob-Type": {"BlockBlob"}},
+	}
+	if err := uploader.Upload(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	if body != "id\n1\n" || blobType != "BlockBlob" {
+		t.Fatalf("body=%q blob type=%q", body, blobType)
+	}
+
+	uploader.URL = func(name string) (string, error) { return server.URL + "/elsewhere/" + name, nil }
+	if err := uploader.Upload(context.Background(), path); err == nil {
+		t.Fatal("expected a rejected upload to fail")
+	}
+}
@@ -0,0 +1,155 @@
+package go_loadgen
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// runUsers runs a closed-loop phase. Each virtual user paces itself to an equal
+// share of the phase rate, or loops as fast as responses and think time allow
+// when the phase has no rate, and never has more than one request outstanding.
+func (r *run) runUsers(p *phaseRun) {
+	var users sync.WaitGroup
+	for user := range p.phase.phase.Users {
+		users.Go(func() { r.runUser(p, user) })
+	}
+	users.Wait()
+}
+
+func (r *run) runUser(p *phaseRun, user uint64) {
+	phase := p.phase
+	start, end := phase.phase.StartAt, phase.phase.Duration
+	timer := r.workload.clock.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	random := phaseRandom{state: splitMix64(phase.seed + user + 1)}
+	done := make(chan struct{}, 1)
+	paced := phase.phase.RPS != 0
+	var at, interval time.Duration
+	if paced {
+		interval = phase.userInterval(0)
+		at = time.Duration(float64(interval) * float64(user) / float64(phase.phase.Users))
+	}
+	for resume := r.from - start; at < resume; {
+		if !paced {
+			at = resume
+			break
+		}
+		at += phase.userInterval(at)
+	}
+	for at <= end {
+		if !r.waitUntil(timer, start+at, phase.spin()) {
+			return
+		}
+		arrival := r.arrivalAt(p, at)
+		arrival.ctx.(*arrivalContext).user = int(user) + 1
+		if paced {
+			interval = phase.userInterval(at)
+			at += interval
+		}
+		issued := r.admit(p) && r.dispatch(p, arrival, phase.chooser.choose(&random), done)
+		if issued {
+			for !waitForCompletion(r.controlCtx, r.workload.clock, timer, r.at(start+end), done) {
+				// A pause moves the phase end while the user waits.
+				if r.controlCtx.Err() != nil || !r.workload.clock.Now().Before(r.at(start+end)) {
+					return
+				}
+			}
+		}
+		if think := phase.phase.Think.sample(&random); think > 0 {
+			if !r.waitUntil(timer, r.elapsed()+think, 0) {
+				return
+			}
+		}
+
+		now := r.elapsed() - start
+		if !paced {
+			// Unpaced users retry a rejected arrival after one scheduler
+			// resolution rather than spinning against MaxInFlight.
+			at = now
+			if !issued {
+				at += phase.resolution
+			}
+			continue
+		}
+		// A slow response delays the user; slots that passed meanwhile are
+		// reported as missed rather than sent back to back.
+		for ; at <= end && now >= at+interval; at += interval {
+			p.counts.scheduled.Add(1)
+			p.counts.missed.Add(1)
+		}
+	}
+}
+
+// userInterval is the gap between one virtual user's requests at the phase rate.
+func (p *compiledPhase) userInterval(elapsed time.Duration) time.Duration {
+	return max(time.Duration(float64(time.Second)*float64(p.phase.Users)/float64(p.rateAt(elapsed))), 1)
+}
+
+// waitForCompletion waits for an outstanding request until the phase ends. Requests
+// still running at the phase boundary are left to the workload drain.
+func waitForCompletion(ctx context.Context, clock Clock, timer Timer, end time.Time, done <-chan struct{}) bool {
+	timer.Reset(end.Sub(clock.Now()))
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return false
+	case <-done:
+		return true
+	}
+}
+
+// ThinkDistribution selects how think times are drawn.
+type ThinkDistribution uint8
+
+const (
+	// FixedThink always waits Duration. It is the default.
+	FixedThink ThinkDistribution = iota
+	// UniformThink waits between Duration and Max.
+	UniformThink
+	// ExponentialThink waits an exponentially distributed time with mean
+	// Duration, capped at Max when Max is non-zero.
+	ExponentialThink
+)
+
+// ThinkTime is the pause a virtual user takes after each response before its
+// next request. The zero value does not pause.
+type ThinkTime struct {
+	Distribution ThinkDistribution
+	Duration     time.Duration
+	Max          time.Duration
+}
+
+func (t ThinkTime) validate() error {
+	if t.Duration < 0 || t.Max < 0 || t.Distribution > ExponentialThink {
+		return errors.New("think time must use a known distribution and non-negative durations")
+	}
+	if t.Distribution == UniformThink && t.Max < t.Duration {
+		return errors.New("uniform think time maximum must not be below its minimum")
+	}
+	return nil
+}
+
+func (t ThinkTime) sample(random *phaseRandom) time.Duration {
+	switch t.Distribution {
+	case UniformThink:
+		if t.Max == t.Duration {
+			return t.Duration
+		}
+		return t.Duration + time.Duration(random.next()%uint64(t.Max-t.Duration+1))
+	case ExponentialThink:
+		uniform := (float64(random.next()>>11) + 1) / (1 << 53)
+		think := time.Duration(-math.Log(uniform) * float64(t.Duration))
+		if t.Max > 0 {
+			think = min(think, t.Max)
+		}
+		return think
+	default:
+		return t.Duration
+	}
+}
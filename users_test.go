@@ -0,0 +1,60 @@
+package go_loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUnpacedUsersLoopWithThinkTime(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(2 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases: []Phase{{
+			Duration: 100 * time.Millisecond,
+			Users:    3,
+			Think:    ThinkTime{Duration: 8 * time.Millisecond},
+			Targets:  []Target{{Endpoint: "one", Weight: 1}},
+		}},
+	})
+
+	report := mustRun(t, workload)
+	// Each user completes a request and thinks roughly every 10ms.
+	if report.PeakInFlight > 3 || report.Issued < 15 || report.Issued > 36 {
+		t.Fatalf("peak=%d issued=%d, want three users cycling every ~10ms", report.PeakInFlight, report.Issued)
+	}
+	if report.Missed != 0 || report.Scheduled != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d missed=%d, want no missed slots without a rate", report.Scheduled, report.Issued, report.Missed)
+	}
+}
+
+func TestThinkTimeDistributions(t *testing.T) {
+	random := phaseRandom{state: splitMix64(1)}
+	uniform := ThinkTime{Distribution: UniformThink, Duration: 10 * time.Millisecond, Max: 20 * time.Millisecond}
+	exponential := ThinkTime{Distribution: ExponentialThink, Duration: 10 * time.Millisecond}
+	capped := ThinkTime{Distribution: ExponentialThink, Duration: 10 * time.Millisecond, Max: 15 * time.Millisecond}
+	const samples = 100_000
+	var exponentialTotal time.Duration
+	for range samples {
+		if think := uniform.sample(&random); think < uniform.Duration || think > uniform.Max {
+			t.Fatalf("uniform think time %s outside [%s, %s]", think, uniform.Duration, uniform.Max)
+		}
+		if think := capped.sample(&random); think > capped.Max {
+			t.Fatalf("capped think time %s above %s", think, capped.Max)
+		}
+		exponentialTotal += exponential.sample(&random)
+	}
+	if mean := exponentialTotal / samples; mean < 9*time.Millisecond || mean > 11*time.Millisecond {
+		t.Fatalf("exponential think time mean=%s, want approximately 10ms", mean)
+	}
+	if got := (ThinkTime{Duration: time.Second}).sample(&random); got != time.Second {
+		t.Fatalf("fixed think time=%s, want 1s", got)
+	}
+	if err := (ThinkTime{Distribution: UniformThink, Duration: time.Second}).validate(); err == nil {
+		t.Fatal("expected uniform think time with a maximum below its minimum to be rejected")
+	}
+}
@@ -4,13 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"reflect"
+	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-const schedulerResolution = time.Millisecond
+const (
+	// defaultResolution is the shortest gap between scheduler wake-ups unless
+	// Spec.Resolution overrides it.
+	defaultResolution = time.Millisecond
+	// spinWindow is how early a sub-millisecond scheduler stops sleeping and
+	// busy-waits, since timers alone are not that precise.
+	spinWindow = 200 * time.Microsecond
+)
 
 // Target assigns part of a phase's offered rate to an endpoint. Weight must be positive.
 type Target struct {
@@ -26,87 +37,398 @@ type Ramp struct {
 	Every time.Duration
 }
 
-// Phase schedules an open-loop offered rate. RPS is the total rate before target splitting.
+// Burst issues Size arrivals at once every Every, starting when the phase starts.
+type Burst struct {
+	Size  uint64
+	Every time.Duration
+}
+
+// Phase schedules traffic for a window of the run: an open-loop offered rate,
+// or closed-loop virtual users when Users is set. RPS is the total rate before
+// target splitting.
 type Phase struct {
+	// Name identifies the phase to clients through PhaseFromContext.
+	Name string
+	// Tags label the phase's requests, for example with an experiment variant.
+	// Clients read them through PhaseFromContext, and CSVCollector can write
+	// them as extra columns.
+	Tags map[string]string
+	// Idle sends no traffic for Duration while keeping the run alive, for
+	// example to let autoscalers scale back down between experiments. Idle
+	// phases set only their name, tags, and timing.
+	Idle     bool
 	StartAt  time.Duration
 	Duration time.Duration
 	RPS      uint64
 	Ramp     *Ramp
+	Arrivals Arrivals
 	Targets  []Target
+	// Noise varies the scheduled rate by up to that fraction each second, such
+	// as 0.1 for 10% either way, so constant phases fluctuate around their
+	// nominal rate like real traffic. Draws follow Spec.Seed, so Plan and
+	// repeated runs offer the same rates. Rates set with SetRate or by a
+	// controller are not varied.
+	Noise float64
+
+	// Trace replays recorded arrivals instead of a rate. Offsets are relative to
+	// the phase start, ascending, and within Duration; RPS must then be zero.
+	Trace []time.Duration
+	// Burst issues fixed-size batches instead of a rate; RPS must then be zero.
+	Burst *Burst
+	// Pacer builds a custom schedule, such as NewTokenBucket, for each run
+	// instead of a rate; RPS must then be zero.
+	Pacer func() Pacer
+	// Schedule names a pacer registered with RegisterPacer. Its factory
+	// receives the phase and interprets RPS and Ramp itself.
+	Schedule string
+	// Users runs the phase closed-loop with that many virtual users. Each user
+	// paces itself to an equal share of the rate and waits for its previous
+	// request to complete before issuing the next. Zero keeps the phase open-loop.
+	// With zero RPS, users loop as fast as responses and Think allow.
+	Users uint64
+	// Think is the pause each virtual user takes after a response.
+	Think ThinkTime
+	// WarmUp sends requests issued during the phase's first WarmUp without
+	// collecting their results, so cold connection pools and caches do not
+	// skew measurements. CollectWarmUp collects them anyway; clients can then
+	// tag results using IsWarmUp on the request context.
+	WarmUp        time.Duration
+	CollectWarmUp bool
+	// DrainTimeout cancels the phase's outstanding requests that long after the
+	// phase ends, independently of other phases. Zero defers to Spec.DrainTimeout.
+	DrainTimeout time.Duration
+	// RequestTimeout bounds each of the phase's requests like
+	// Spec.RequestTimeout, so slow uploads and quick pings can share a
+	// workload. Zero defers to Spec.RequestTimeout.
+	RequestTimeout time.Duration
+	// Controller adapts the rate every ControlEvery, starting from RPS and any
+	// ramp. SetRate takes precedence over it.
+	Controller   RateController
+	ControlEvery time.Duration
+	// ErrorBudget stops the whole run early once the phase's failed results,
+	// excluding warm-up, exceed it. Results report failure through Outcome.
+	ErrorBudget *ErrorBudget
+}
+
+// ErrorBudget bounds the failures a phase tolerates before the run is stopped,
+// so a soak test against a dead target ends within seconds. Outstanding
+// requests still drain. Zero fields disable their limit.
+type ErrorBudget struct {
+	// MaxErrors is the number of failed results tolerated.
+	MaxErrors uint64
+	// MaxRate is the tolerated fraction of failed results, checked once at
+	// least MinRequests requests have completed.
+	MaxRate     float64
+	MinRequests uint64
+}
+
+// Sequence lays phases out back to back, separated by idle phases of gap,
+// replacing their start times. The result can be extended or edited before
+// it is used as Spec.Phases.
+func Sequence(gap time.Duration, phases ...Phase) []Phase {
+	sequence := make([]Phase, 0, 2*len(phases))
+	var at time.Duration
+	for i, phase := range phases {
+		if i > 0 && gap > 0 {
+			sequence = append(sequence, Phase{Idle: true, StartAt: at, Duration: gap})
+			at += gap
+		}
+		phase.StartAt = at
+		sequence = append(sequence, phase)
+		at += phase.Duration
+	}
+	return sequence
 }
 
 // Spec describes a workload before endpoint names and target weights are compiled.
 type Spec struct {
+	// Name identifies the workload to clients and collectors through
+	// PhaseFromContext.
+	Name      string
 	Duration  time.Duration
 	Seed      uint64
 	Endpoints map[string]Endpoint
 	Phases    []Phase
+	// Sequential runs phases one after another in order, computing each
+	// StartAt from the durations of the phases before it, so editing a
+	// duration never leaves later offsets out of sync. Phases must leave
+	// StartAt zero, and a zero Duration defaults to the phases' total.
+	Sequential bool
+	// Repeat runs the whole schedule, Duration long, this many times back to
+	// back, for soak tests that loop one profile. RepeatFor instead repeats it
+	// as many whole times as fit in a total duration. Each iteration's phases
+	// appear in turn in Report.Phases and are indexed that way by SetRate,
+	// and requests carry their iteration in PhaseInfo.
+	Repeat    int
+	RepeatFor time.Duration
 
 	// MaxInFlight bounds outstanding requests. Zero leaves it unbounded.
 	// When full, arrivals are dropped so the schedule remains open-loop.
 	MaxInFlight uint64
+	// WhenFull selects what happens to arrivals while MaxInFlight is reached.
+	WhenFull FullPolicy
+	// MaxRPS caps the combined offered rate of overlapping phases, such as a
+	// target's safe ceiling. NewWorkload rejects schedules where a phase and
+	// every phase overlapping it could exceed it at their peak rates, and
+	// SetRate and rate controllers are held to the headroom the overlapping
+	// phases leave. Only phases scheduled from RPS are counted. Zero disables it.
+	MaxRPS uint64
 	// DrainTimeout cancels outstanding requests after scheduling ends. Zero waits indefinitely.
 	DrainTimeout time.Duration
+	// RequestTimeout bounds each request through its context deadline. Requests
+	// still running at the deadline are reported in TimedOut. Zero disables it.
+	RequestTimeout time.Duration
+	// Resolution is the shortest gap between scheduler wake-ups; faster rates are
+	// issued in batches of this length. Zero uses one millisecond. It must divide
+	// a second evenly, and values below a millisecond busy-wait one CPU per phase
+	// to keep per-request spacing accurate.
+	Resolution time.Duration
+	// Workers executes requests on a fixed pool of goroutines instead of one
+	// goroutine per request. Zero disables the pool. Arrivals that find every
+	// worker busy and the pool's queue, also Workers long, full are dropped.
+	Workers uint64
+	// HandleSignals stops scheduling when the process receives SIGINT or
+	// SIGTERM. Outstanding requests drain as at the end of a run, subject to
+	// DrainTimeout, and Run returns ErrInterrupted, so deferred collector Close
+	// calls still flush results.
+	HandleSignals bool
+	// Progress receives phase events and a tick every ProgressEvery, one second
+	// by default. Events are dropped rather than delaying the run when the
+	// channel is full, and Run does not close it.
+	Progress      chan<- ProgressEvent
+	ProgressEvery time.Duration
+	// StopConditions stop the run when recent results breach a limit.
+	StopConditions []StopCondition
+	// Thresholds are evaluated when the run ends and reported in
+	// Report.Thresholds; Report.Passed gates CI on them.
+	Thresholds []Threshold
+	// Observers receive every measured request's outcome as it completes,
+	// for live metrics such as PrometheusCollector.
+	Observers []Observer
+	// Clock is the time source runs schedule against. Nil uses the system
+	// clock; tests can pass a FakeClock.
+	Clock Clock
+}
+
+// FullPolicy decides the fate of an arrival that finds MaxInFlight reached.
+type FullPolicy uint8
+
+const (
+	// DropWhenFull drops the arrival and reports it in Dropped. It is the default.
+	DropWhenFull FullPolicy = iota
+	// DelayWhenFull holds the phase until a request completes and reports the
+	// arrival in Delayed. Later arrivals overtaken by the wait are reported as missed.
+	DelayWhenFull
+)
+
+// ErrErrorBudgetExceeded is returned by Run when a phase's ErrorBudget stopped it.
+var ErrErrorBudgetExceeded = errors.New("error budget exceeded")
+
+// Counts are the arrival and request outcomes of a run or phase. Scheduled is
+// the number of arrivals requested by phases; Issued is the number passed to
+// endpoint execution.
+type Counts struct {
+	Scheduled uint64
+	Issued    uint64
+	Dropped   uint64
+	Delayed   uint64
+	Missed    uint64
+	Completed uint64
+	TimedOut  uint64
+	// Failed counts completed requests whose results reported failure.
+	Failed uint64
+	// WarmUp counts issued requests that fell within a phase warm-up.
+	WarmUp uint64
 }
 
-// Report contains the actual load generator outcome. Scheduled is the number of
-// arrivals requested by phases; Issued is the number passed to endpoint execution.
+func (c *Counts) add(other Counts) {
+	c.Scheduled += other.Scheduled
+	c.Issued += other.Issued
+	c.Dropped += other.Dropped
+	c.Delayed += other.Delayed
+	c.Missed += other.Missed
+	c.Completed += other.Completed
+	c.TimedOut += other.TimedOut
+	c.Failed += other.Failed
+	c.WarmUp += other.WarmUp
+}
+
+// PhaseReport is the outcome of one phase, in Spec.Phases order.
+type PhaseReport struct {
+	Name string
+	Counts
+}
+
+// Report contains the actual load generator outcome. Its counts are the totals
+// of Phases.
 type Report struct {
-	Scheduled     uint64
-	Issued        uint64
-	Dropped       uint64
-	Missed        uint64
-	Completed     uint64
+	Counts
+	Phases []PhaseReport
+	// Thresholds holds the evaluation of Spec.Thresholds in order.
+	Thresholds []ThresholdResult
+	// MeanLag and MaxLag measure how long after its scheduled time each request
+	// started. Growing lag means the generator, not the target, is delaying
+	// requests; latency measured from ScheduledAt includes it.
+	MeanLag       time.Duration
+	MaxLag        time.Duration
 	PeakInFlight  uint64
 	DrainTimedOut bool
+	// Paused is the time scheduling was suspended by Pause.
+	Paused time.Duration
 	// SchedulingDuration ends when no phase can issue another arrival.
 	SchedulingDuration time.Duration
 	// Duration includes the post-scheduling drain.
 	Duration time.Duration
+	// Checkpoint is where scheduling stopped, for resuming an interrupted run
+	// with RunFrom.
+	Checkpoint Checkpoint
+	// CollectorErrors holds the errors reported by endpoint collectors and
+	// observers that implement ErrorReporter, such as a full disk truncating
+	// a result file. Run also returns them.
+	CollectorErrors []error
 }
 
-// Workload is an immutable, validated workload ready to run.
+// Workload is a validated workload ready to run. Its definition is immutable;
+// Pause and Resume control the runs in progress.
 type Workload struct {
-	duration     time.Duration
-	seed         uint64
-	phases       []compiledPhase
-	maxInFlight  uint64
-	drainTimeout time.Duration
+	duration       time.Duration
+	seed           uint64
+	phases         []compiledPhase
+	maxInFlight    uint64
+	whenFull       FullPolicy
+	drainTimeout   time.Duration
+	requestTimeout time.Duration
+	workers        uint64
+	handleSignals  bool
+	progress       chan<- ProgressEvent
+	progressEvery  time.Duration
+	stopConditions []StopCondition
+	thresholds     []Threshold
+	observers      []Observer
+	endpoints      map[string]Endpoint
+	// batching are the endpoints queueing results for a BatchCollector.
+	batching []Endpoint
+	clock    Clock
+	pause    pauseClock
 }
 
 type compiledPhase struct {
-	phase   Phase
-	chooser aliasChooser
-	seed    uint64
+	phase      Phase
+	info       PhaseInfo
+	chooser    aliasChooser
+	seed       uint64
+	resolution time.Duration
+	// override replaces the scheduled rate when non-zero. See SetRate.
+	override atomic.Uint64
+	// adapted is the rate chosen by the phase's controller, if any.
+	adapted atomic.Uint64
+	// ceiling bounds override and adapted under Spec.MaxRPS. Zero is unbounded.
+	ceiling uint64
 }
 
 // NewWorkload validates a workload and compiles endpoint routing. It performs no
 // allocation or endpoint lookup during request dispatch.
 func NewWorkload(spec Spec) (*Workload, error) {
+	if spec.Sequential {
+		for i, phase := range spec.Phases {
+			if phase.StartAt != 0 {
+				return nil, fmt.Errorf("phase %d: sequential phases cannot set a start offset", i)
+			}
+		}
+		spec.Phases = Sequence(0, spec.Phases...)
+		if spec.Duration == 0 && len(spec.Phases) > 0 {
+			last := spec.Phases[len(spec.Phases)-1]
+			spec.Duration = last.StartAt + last.Duration
+		}
+	}
 	if spec.Duration <= 0 {
 		return nil, errors.New("workload duration must be positive")
 	}
 	if len(spec.Phases) == 0 {
 		return nil, errors.New("workload must contain at least one phase")
 	}
+	iterations := 1
+	switch {
+	case spec.Repeat < 0 || spec.RepeatFor < 0 || (spec.Repeat != 0 && spec.RepeatFor != 0):
+		return nil, errors.New("repeat must be a non-negative count or total duration, not both")
+	case spec.RepeatFor != 0:
+		if iterations = int(spec.RepeatFor / spec.Duration); iterations == 0 {
+			return nil, errors.New("repeat duration must fit at least one iteration")
+		}
+	case spec.Repeat != 0:
+		iterations = spec.Repeat
+	}
+	phases := len(spec.Phases)
 	if len(spec.Endpoints) == 0 {
 		return nil, errors.New("workload must contain at least one endpoint")
 	}
-	if spec.DrainTimeout < 0 {
-		return nil, errors.New("drain timeout cannot be negative")
+	if spec.DrainTimeout < 0 || spec.RequestTimeout < 0 || spec.ProgressEvery < 0 {
+		return nil, errors.New("drain and request timeouts and progress interval cannot be negative")
+	}
+	if spec.Resolution < 0 || spec.Resolution > time.Second || (spec.Resolution != 0 && time.Second%spec.Resolution != 0) {
+		return nil, errors.New("resolution must divide a second evenly")
+	}
+	resolution := spec.Resolution
+	if resolution == 0 {
+		resolution = defaultResolution
+	}
+	if spec.WhenFull > DelayWhenFull {
+		return nil, errors.New("unknown full policy")
 	}
 
 	w := &Workload{
-		duration:     spec.Duration,
-		seed:         spec.Seed,
-		phases:       make([]compiledPhase, len(spec.Phases)),
-		maxInFlight:  spec.MaxInFlight,
-		drainTimeout: spec.DrainTimeout,
+		duration:       spec.Duration * time.Duration(iterations),
+		seed:           spec.Seed,
+		phases:         make([]compiledPhase, 0, phases*iterations),
+		maxInFlight:    spec.MaxInFlight,
+		whenFull:       spec.WhenFull,
+		drainTimeout:   spec.DrainTimeout,
+		requestTimeout: spec.RequestTimeout,
+		workers:        spec.Workers,
+		handleSignals:  spec.HandleSignals,
+		progress:       spec.Progress,
+		progressEvery:  spec.ProgressEvery,
+		stopConditions: slices.Clone(spec.StopConditions),
+		thresholds:     slices.Clone(spec.Thresholds),
+		observers:      slices.Clone(spec.Observers),
+		endpoints:      maps.Clone(spec.Endpoints),
+		clock:          spec.Clock,
+	}
+	if isNil(w.clock) {
+		w.clock = systemClock{}
+	}
+	for _, name := range slices.Sorted(maps.Keys(w.endpoints)) {
+		if endpoint := w.endpoints[name]; !isNil(endpoint) && endpoint.batching() {
+			w.batching = append(w.batching, endpoint)
+		}
+	}
+	w.pause.clock = w.clock
+	for i, threshold := range spec.Thresholds {
+		if err := threshold.validate(); err != nil {
+			return nil, fmt.Errorf("threshold %d: %w", i, err)
+		}
+	}
+	for i, observer := range spec.Observers {
+		if isNil(observer) {
+			return nil, fmt.Errorf("observer %d is nil", i)
+		}
+	}
+	for i, condition := range spec.StopConditions {
+		if err := condition.validate(); err != nil {
+			return nil, fmt.Errorf("stop condition %d: %w", i, err)
+		}
+	}
+	if w.progressEvery == 0 {
+		w.progressEvery = time.Second
 	}
 	for i, phase := range spec.Phases {
 		if err := validatePhase(spec.Duration, phase); err != nil {
 			return nil, fmt.Errorf("phase %d: %w", i, err)
 		}
+	}
+	for i := range phases * iterations {
+		phase := spec.Phases[i%phases]
+		phase.StartAt += spec.Duration * time.Duration(i/phases)
 		endpoints := make([]Endpoint, len(phase.Targets))
 		weights := make([]uint32, len(phase.Targets))
 		for j, target := range phase.Targets {
@@ -116,20 +438,103 @@ func NewWorkload(spec Spec) (*Workload, error) {
 			}
 			endpoints[j], weights[j] = endpoint, target.Weight
 		}
-		chooser, err := newAliasChooser(endpoints, weights)
-		if err != nil {
-			return nil, fmt.Errorf("phase %d: %w", i, err)
+		var chooser aliasChooser
+		var err error
+		if !phase.Idle {
+			if chooser, err = newAliasChooser(endpoints, weights); err != nil {
+				return nil, fmt.Errorf("phase %d: %w", i, err)
+			}
 		}
 		compiled := phase
 		if phase.Ramp != nil {
 			ramp := *phase.Ramp
 			compiled.Ramp = &ramp
 		}
-		w.phases[i] = compiledPhase{phase: compiled, chooser: chooser, seed: splitMix64(spec.Seed + uint64(i))}
+		if phase.Burst != nil {
+			burst := *phase.Burst
+			compiled.Burst = &burst
+		}
+		if phase.ErrorBudget != nil {
+			budget := *phase.ErrorBudget
+			compiled.ErrorBudget = &budget
+		}
+		compiled.Trace = slices.Clone(phase.Trace)
+		compiled.Tags = maps.Clone(phase.Tags)
+		if phase.Schedule != "" {
+			if compiled.Pacer, err = registeredPacer(compiled); err != nil {
+				return nil, fmt.Errorf("phase %d: %w", i, err)
+			}
+		}
+		info := PhaseInfo{Index: i, Iteration: i / phases, Name: phase.Name, Workload: spec.Name, Kind: phase.kind(), Tags: compiled.Tags}
+		w.phases = append(w.phases, compiledPhase{phase: compiled, info: info, chooser: chooser, seed: splitMix64(spec.Seed + uint64(i)), resolution: resolution})
+	}
+	if spec.MaxRPS != 0 {
+		if err := w.capRates(spec.MaxRPS); err != nil {
+			return nil, err
+		}
 	}
 	return w, nil
 }
 
+// capRates checks each rate phase against limit together with every rate
+// phase overlapping it, each at its peak rate, and leaves the phase the
+// remaining headroom as its ceiling.
+func (w *Workload) capRates(limit uint64) error {
+	for i := range w.phases {
+		phase := &w.phases[i]
+		if !phase.phase.hasRate() {
+			continue
+		}
+		total := phase.phase.peakRate()
+		for j := range w.phases {
+			other := w.phases[j].phase
+			if j != i && other.hasRate() && other.StartAt < phase.phase.StartAt+phase.phase.Duration && phase.phase.StartAt < other.StartAt+other.Duration {
+				total += other.peakRate()
+			}
+		}
+		if total > limit {
+			return fmt.Errorf("phase %d: overlapping phases offer up to %d rps, above the maximum of %d", i, total, limit)
+		}
+		phase.ceiling = limit - (total - phase.phase.peakRate())
+	}
+	return nil
+}
+
+// peakRate is the highest rate the phase's own schedule offers.
+func (p Phase) peakRate() uint64 {
+	if p.Ramp != nil {
+		return max(p.RPS, p.Ramp.To)
+	}
+	return p.RPS
+}
+
+// hasRate reports whether the phase is scheduled from RPS.
+func (p Phase) hasRate() bool {
+	return p.Pacer == nil && p.Schedule == "" && len(p.Trace) == 0 && p.Burst == nil && p.RPS != 0
+}
+
+// kind names how the phase schedules arrivals, for PhaseInfo.
+func (p Phase) kind() string {
+	switch {
+	case p.Idle:
+		return "idle"
+	case p.Schedule != "":
+		return p.Schedule
+	case p.Pacer != nil:
+		return "pacer"
+	case len(p.Trace) != 0:
+		return "trace"
+	case p.Burst != nil:
+		return "burst"
+	case p.Users != 0:
+		return "closed"
+	case p.Arrivals == PoissonArrivals:
+		return "poisson"
+	default:
+		return "uniform"
+	}
+}
+
 func validatePhase(workloadDuration time.Duration, phase Phase) error {
 	if phase.StartAt < 0 || phase.Duration <= 0 {
 		return errors.New("start time must be non-negative and duration must be positive")
@@ -137,143 +542,714 @@ func validatePhase(workloadDuration time.Duration, phase Phase) error {
 	if phase.StartAt >= workloadDuration || phase.Duration > workloadDuration-phase.StartAt {
 		return errors.New("phase must fit within workload duration")
 	}
-	if phase.RPS == 0 {
+	if phase.Idle {
+		idle := Phase{Name: phase.Name, Tags: phase.Tags, Idle: true, StartAt: phase.StartAt, Duration: phase.Duration}
+		if !reflect.DeepEqual(phase, idle) {
+			return errors.New("idle phases can only set a name, tags, start, and duration")
+		}
+		return nil
+	}
+	switch {
+	case phase.Pacer != nil || phase.Schedule != "":
+		if err := validatePacer(phase); err != nil {
+			return err
+		}
+	case len(phase.Trace) != 0:
+		if err := validateTrace(phase); err != nil {
+			return err
+		}
+	case phase.Burst != nil:
+		if err := validateBurst(phase); err != nil {
+			return err
+		}
+	case phase.RPS == 0 && (phase.Users == 0 || phase.Ramp != nil):
 		return errors.New("RPS must be positive")
 	}
 	if len(phase.Targets) == 0 {
 		return errors.New("phase must target at least one endpoint")
 	}
+	if phase.DrainTimeout < 0 || phase.RequestTimeout < 0 || phase.WarmUp < 0 {
+		return errors.New("drain and request timeouts and warm-up cannot be negative")
+	}
+	if phase.Arrivals > PoissonArrivals {
+		return errors.New("unknown arrival process")
+	}
+	if phase.Controller != nil && (isNil(phase.Controller) || phase.ControlEvery <= 0 || !phase.hasRate()) {
+		return errors.New("rate controller must be non-nil with a positive interval and a rate to adapt")
+	}
+	if controller, ok := phase.Controller.(*LatencyController); ok && controller.Observe == nil {
+		return errors.New("latency controller needs an Observe function")
+	}
+	if budget := phase.ErrorBudget; budget != nil && (budget.MaxRate < 0 || budget.MaxRate > 1 || (budget.MaxErrors == 0 && budget.MaxRate == 0)) {
+		return errors.New("error budget must set an error count or a rate between zero and one")
+	}
+	if phase.Users != 0 && phase.Arrivals != UniformArrivals {
+		return errors.New("closed-loop phases use uniform arrivals")
+	}
+	if phase.Think != (ThinkTime{}) {
+		if phase.Users == 0 {
+			return errors.New("think time requires virtual users")
+		}
+		if err := phase.Think.validate(); err != nil {
+			return err
+		}
+	}
 	if phase.Ramp != nil {
 		if phase.Ramp.Step == 0 || phase.Ramp.Every <= 0 {
 			return errors.New("ramp step and interval must be positive")
 		}
 	}
+	if phase.Noise < 0 || phase.Noise > 1 || (phase.Noise != 0 && !phase.hasRate()) {
+		return errors.New("noise must be a fraction of at most one on a phase with a rate")
+	}
 	return nil
 }
 
 // Run issues all phase arrivals, then waits for their completion. The supplied
-// context is only external cancellation; phase deadlines never cancel requests.
-func (w *Workload) Run(ctx context.Context) Report {
-	started := time.Now()
+// context is only external cancellation: cancelling it stops scheduling and
+// cancels outstanding requests, and Run returns once they complete. Phase
+// deadlines never cancel requests unless the phase sets its own DrainTimeout.
+//
+// The error reports why a run ended early: the context's cause, exceeded error
+// budgets, or phases that could not be scheduled. The report is valid either way.
+func (w *Workload) Run(ctx context.Context) (Report, error) {
+	return w.runFrom(ctx, 0, nil)
+}
+
+// runFrom runs the schedule from offset from, skipping everything before it.
+// A non-nil handle is attached to the run.
+func (w *Workload) runFrom(ctx context.Context, from time.Duration, handle *Handle) (Report, error) {
 	requestsCtx, cancelRequests := context.WithCancel(ctx)
 	defer cancelRequests()
+	controlCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	r := &run{workload: w, controlCtx: controlCtx, stop: stop, requestsCtx: requestsCtx, started: w.clock.Now().Add(-from), from: from, phases: make([]phaseRun, len(w.phases))}
+	r.pauseBase, _ = w.pause.state()
+	if w.handleSignals {
+		defer r.stopOnSignal()()
+	}
+	if w.workers > 0 {
+		r.queue = make(chan queuedRequest, w.workers)
+		for worker := range int(w.workers) {
+			go r.work(worker)
+		}
+		defer close(r.queue)
+	}
+
+	for i := range r.phases {
+		r.phases[i].phase = &w.phases[i]
+	}
+	if handle != nil {
+		handle.started(r)
+	}
+	// Background goroutines end before Run returns, so no progress event is
+	// sent after it.
+	var background sync.WaitGroup
+	defer background.Wait()
+	done := make(chan struct{})
+	defer close(done)
+	if w.progress != nil {
+		background.Go(func() { r.reportProgress(done) })
+	}
+	if len(w.batching) != 0 {
+		background.Go(func() { r.flushBatches(done) })
+	}
+	if len(w.thresholds) != 0 {
+		r.measured.latencies = newHistogram(defaultHistogramSubBits)
+	}
+	r.windows = make([]stopWindow, len(w.stopConditions))
+	for i := range r.windows {
+		r.windows[i].init(w.stopConditions[i])
+		background.Go(func() { r.watch(&r.windows[i], done) })
+	}
 
-	var report runReport
 	var schedulers sync.WaitGroup
-	var requests sync.WaitGroup
-	for i := range w.phases {
-		phase := &w.phases[i]
-		schedulers.Add(1)
-		go func() {
-			defer schedulers.Done()
-			w.runPhase(ctx, requestsCtx, started, phase, &report, &requests)
-		}()
+	for i := range r.phases {
+		p := &r.phases[i]
+		schedulers.Go(func() { r.runPhase(p) })
 	}
 	schedulers.Wait()
-	schedulingDuration := time.Since(started)
+	schedulingDuration := r.since(r.started) - from
+	// Throughput is measured over the time scheduling was not paused.
+	activeDuration := schedulingDuration - r.shift()
+	checkpoint := Checkpoint{Seed: w.seed, Phases: len(w.phases), Offset: min(r.elapsed(), w.duration)}
+	for i := range r.phases {
+		if r.phases[i].stopped {
+			checkpoint.Offset = min(checkpoint.Offset, r.phases[i].stoppedAt)
+		}
+	}
 
-	var timedOut atomic.Bool
-	var timer *time.Timer
+	var timer Timer
 	if w.drainTimeout > 0 {
-		timer = time.AfterFunc(w.drainTimeout, func() {
-			if report.inFlight.Load() != 0 {
-				timedOut.Store(true)
+		timer = w.clock.AfterFunc(w.drainTimeout, func() {
+			if r.report.inFlight.Load() != 0 {
+				r.drainTimedOut.Store(true)
 				cancelRequests()
 			}
 		})
 	}
-	requests.Wait()
+	r.requests.Wait()
 	if timer != nil {
 		timer.Stop()
 	}
+	for _, endpoint := range w.batching {
+		endpoint.flush()
+	}
 
-	return Report{
-		Scheduled:          report.scheduled.Load(),
-		Issued:             report.issued.Load(),
-		Dropped:            report.dropped.Load(),
-		Missed:             report.missed.Load(),
-		Completed:          report.completed.Load(),
-		PeakInFlight:       report.peakInFlight.Load(),
-		DrainTimedOut:      timedOut.Load(),
+	report := Report{
+		Phases:             make([]PhaseReport, len(r.phases)),
+		MaxLag:             time.Duration(r.report.maxLag.Load()),
+		PeakInFlight:       r.report.peakInFlight.Load(),
+		Paused:             r.shift(),
+		DrainTimedOut:      r.drainTimedOut.Load(),
 		SchedulingDuration: schedulingDuration,
-		Duration:           time.Since(started),
+		Duration:           r.since(r.started) - from,
+		Checkpoint:         checkpoint,
+	}
+	for i := range r.phases {
+		phase := PhaseReport{Name: w.phases[i].phase.Name, Counts: r.phases[i].counts.load()}
+		report.Phases[i] = phase
+		report.add(phase.Counts)
+	}
+	if len(w.thresholds) != 0 {
+		report.Thresholds = r.measured.evaluate(w.thresholds, activeDuration)
+	}
+	if report.Completed != 0 {
+		report.MeanLag = time.Duration(r.report.lag.Load() / report.Completed)
 	}
+	report.CollectorErrors = w.collectorErrors()
+	for _, err := range report.CollectorErrors {
+		r.fail(err)
+	}
+	if ctx.Err() != nil {
+		r.fail(context.Cause(ctx))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return report, errors.Join(r.errs...)
+}
+
+// flushBatches hands queued results to batch collectors every resultBatchInterval
+// until done is closed.
+func (r *run) flushBatches(done <-chan struct{}) {
+	ticker := r.workload.clock.NewTicker(resultBatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+			for _, endpoint := range r.workload.batching {
+				endpoint.flush()
+			}
+		}
+	}
+}
+
+// collectorErrors gathers the errors of collectors and observers that
+// implement ErrorReporter, endpoints first in name order.
+func (w *Workload) collectorErrors() []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(w.endpoints)) {
+		if endpoint := w.endpoints[name]; !isNil(endpoint) {
+			if err := endpoint.collectorErr(); err != nil {
+				errs = append(errs, fmt.Errorf("endpoint %q collector: %w", name, err))
+			}
+		}
+	}
+	for i, observer := range w.observers {
+		if reporter, ok := observer.(ErrorReporter); ok {
+			if err := reporter.Err(); err != nil {
+				errs = append(errs, fmt.Errorf("observer %d: %w", i, err))
+			}
+		}
+	}
+	return errs
 }
 
 type runReport struct {
-	scheduled    atomic.Uint64
-	issued       atomic.Uint64
-	dropped      atomic.Uint64
-	missed       atomic.Uint64
-	completed    atomic.Uint64
+	lag          atomic.Uint64
+	maxLag       atomic.Uint64
 	inFlight     atomic.Uint64
 	peakInFlight atomic.Uint64
 }
 
-func (w *Workload) runPhase(controlCtx, requestsCtx context.Context, workloadStart time.Time, phase *compiledPhase, report *runReport, requests *sync.WaitGroup) {
-	start := workloadStart.Add(phase.phase.StartAt)
-	end := start.Add(phase.phase.Duration)
-	timer := time.NewTimer(time.Hour)
-	if !timer.Stop() {
-		<-timer.C
+// phaseCounts accumulates a phase's Counts while it runs.
+type phaseCounts struct {
+	scheduled atomic.Uint64
+	issued    atomic.Uint64
+	dropped   atomic.Uint64
+	delayed   atomic.Uint64
+	missed    atomic.Uint64
+	completed atomic.Uint64
+	timedOut  atomic.Uint64
+	failed    atomic.Uint64
+	warmUp    atomic.Uint64
+}
+
+func (c *phaseCounts) load() Counts {
+	return Counts{
+		Scheduled: c.scheduled.Load(),
+		Issued:    c.issued.Load(),
+		Dropped:   c.dropped.Load(),
+		Delayed:   c.delayed.Load(),
+		Missed:    c.missed.Load(),
+		Completed: c.completed.Load(),
+		TimedOut:  c.timedOut.Load(),
+		Failed:    c.failed.Load(),
+		WarmUp:    c.warmUp.Load(),
+	}
+}
+
+// run holds the state of a single Workload.Run call.
+type run struct {
+	workload *Workload
+	// controlCtx stops scheduling; requestsCtx is passed to endpoints and is
+	// additionally cancelled by the drain timeout.
+	controlCtx  context.Context
+	requestsCtx context.Context
+	// stop cancels controlCtx when the run is aborted.
+	stop    context.CancelFunc
+	started time.Time
+	// from is the schedule offset the run resumed at; arrivals before it are skipped.
+	from time.Duration
+	// pauseBase is the workload's paused total when the run started.
+	pauseBase time.Duration
+	report    runReport
+	phases    []phaseRun
+	windows   []stopWindow
+	measured  thresholdResults
+	requests  sync.WaitGroup
+	queue     chan queuedRequest
+	// released wakes arrivals delayed by DelayWhenFull.
+	released      broadcast
+	drainTimedOut atomic.Bool
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// fail records why the run ended early.
+func (r *run) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+// abort stops scheduling in every phase because of err.
+func (r *run) abort(err error) {
+	r.fail(err)
+	r.stop()
+}
+
+// phaseRun holds the state of one phase within a run.
+type phaseRun struct {
+	phase *compiledPhase
+	// ctx is passed to the phase's requests. It is cancelled by the phase's
+	// own drain timeout, if any, in addition to the run's.
+	ctx context.Context
+	// warmUpCtx is passed to requests issued during the phase warm-up.
+	warmUpCtx context.Context
+	requests  sync.WaitGroup
+	counts    phaseCounts
+	// stoppedAt is the schedule offset of the first arrival the phase did
+	// not schedule because the run stopped, if stopped.
+	stoppedAt time.Duration
+	stopped   bool
+	// measured and measuredFailed count results outside the warm-up for the
+	// error budget.
+	measured       atomic.Uint64
+	measuredFailed atomic.Uint64
+	budgetExceeded atomic.Bool
+}
+
+// arrival describes when and with which context requests were scheduled.
+// Arrivals in one batch share it.
+type arrival struct {
+	ctx       context.Context
+	scheduled time.Time
+	warmUp    bool
+}
+
+// arrivalAt returns the arrival scheduled at offset from the phase start.
+func (r *run) arrivalAt(p *phaseRun, offset time.Duration) arrival {
+	a := arrival{ctx: p.ctx, scheduled: r.at(p.phase.phase.StartAt + offset)}
+	if offset < p.phase.phase.WarmUp {
+		a.ctx, a.warmUp = p.warmUpCtx, true
+	}
+	ctx := &arrivalContext{Context: a.ctx, at: a.scheduled, phase: &p.phase.info, seed: p.phase.seed ^ uint64(offset)}
+	if p.phase.phase.hasRate() {
+		ctx.rps = p.phase.rateAt(offset)
+	}
+	a.ctx = ctx
+	return a
+}
+
+type queuedRequest struct {
+	phase    *phaseRun
+	arrival  arrival
+	endpoint Endpoint
+	done     chan<- struct{}
+}
+
+func (r *run) runPhase(p *phaseRun) {
+	phase := p.phase
+	p.ctx = r.requestsCtx
+	if phase.phase.DrainTimeout > 0 {
+		ctx, cancel := context.WithCancel(r.requestsCtx)
+		p.ctx = ctx
+		defer r.drainPhase(p, cancel)
 	}
+	if phase.phase.WarmUp > 0 {
+		p.warmUpCtx = context.WithValue(p.ctx, warmUpKey{}, warmUp{collect: phase.phase.CollectWarmUp})
+	}
+	r.schedulePhase(p)
+}
+
+func (p *phaseRun) stop(offset time.Duration) {
+	p.stoppedAt, p.stopped = offset, true
+}
+
+// drainPhase cancels the phase's outstanding requests once its drain timeout
+// elapses after scheduling ends, without waiting for other phases.
+func (r *run) drainPhase(p *phaseRun, cancel context.CancelFunc) {
+	r.requests.Add(1)
+	go func() {
+		defer r.requests.Done()
+		var drained atomic.Bool
+		timer := r.workload.clock.AfterFunc(p.phase.phase.DrainTimeout, func() {
+			if !drained.Load() {
+				r.drainTimedOut.Store(true)
+				cancel()
+			}
+		})
+		p.requests.Wait()
+		drained.Store(true)
+		timer.Stop()
+		cancel()
+	}()
+}
+
+func (r *run) schedulePhase(p *phaseRun) {
+	phase := p.phase
+	timer := r.workload.clock.NewTimer(time.Hour)
+	timer.Stop()
 	defer timer.Stop()
-	if !waitUntilTimer(controlCtx, timer, start) {
+	if phase.phase.StartAt+phase.phase.Duration <= r.from {
+		return
+	}
+	if !r.waitUntil(timer, phase.phase.StartAt, 0) {
+		p.stop(max(phase.phase.StartAt, r.from))
+		return
+	}
+	r.notify(ProgressEvent{Kind: PhaseStarted, Phase: phase.info.Index})
+	defer r.notify(ProgressEvent{Kind: PhaseFinished, Phase: phase.info.Index})
+	if phase.phase.Idle {
+		if !r.waitUntil(timer, phase.phase.StartAt+phase.phase.Duration, 0) {
+			p.stop(r.elapsed())
+		}
+		return
+	}
+	if phase.phase.Controller != nil {
+		phase.adapted.Store(0)
+		ctx, cancel := context.WithCancel(r.controlCtx)
+		// The controller ends with the phase, so NextRate is never called
+		// after Run returns.
+		var controller sync.WaitGroup
+		controller.Go(func() { r.controlRate(ctx, phase) })
+		defer func() {
+			cancel()
+			controller.Wait()
+		}()
+	}
+	if phase.phase.Users != 0 {
+		r.runUsers(p)
+		if r.controlCtx.Err() != nil {
+			p.stop(r.elapsed())
+		}
 		return
 	}
 
 	random := phaseRandom{state: phase.seed}
-	next := start
-	var remainder uint64
+	pacer := phase.newPacer()
+	if pacer == nil {
+		r.fail(fmt.Errorf("phase %d: pacer is nil", phase.info.Index))
+		return
+	}
 	for {
-		rate := phase.rateAt(next.Sub(start))
-		interval := batchInterval(rate)
-		next = next.Add(interval)
-		if next.After(end) {
+		batch, ok := pacer.Next()
+		if !ok || batch.At > phase.phase.Duration {
 			return
 		}
-		if !waitUntilTimer(controlCtx, timer, next) {
+		if batch.Deadline == 0 {
+			batch.Deadline = batch.At + phase.resolution
+		}
+		if phase.phase.StartAt+batch.At < r.from {
+			// Replay the endpoint choices of skipped arrivals so a resumed
+			// run continues the original sequence.
+			for range batch.Count {
+				random.next()
+			}
+			continue
+		}
+		if !r.waitUntil(timer, phase.phase.StartAt+batch.At, phase.spin()) {
+			p.stop(phase.phase.StartAt + batch.At)
 			return
 		}
 
 		// Do not replay arrivals after a loader pause: report them instead of
 		// creating an artificial catch-up burst against the target.
-		for time.Since(next) >= interval {
-			count := arrivalsForInterval(rate, interval, &remainder)
-			report.scheduled.Add(count)
-			report.missed.Add(count)
-			next = next.Add(interval)
-			if next.After(end) {
-				return
-			}
-			rate = phase.rateAt(next.Sub(start))
-			interval = batchInterval(rate)
+		if r.elapsed() >= phase.phase.StartAt+batch.Deadline {
+			p.counts.scheduled.Add(batch.Count)
+			p.counts.missed.Add(batch.Count)
+			continue
 		}
 
-		count := arrivalsForInterval(rate, interval, &remainder)
-		for range count {
-			if controlCtx.Err() != nil {
+		arrival := r.arrivalAt(p, batch.At)
+		for range batch.Count {
+			if r.controlCtx.Err() != nil {
+				// The rest of the batch is not resumed.
+				p.stop(phase.phase.StartAt + batch.At + 1)
 				return
 			}
-			report.scheduled.Add(1)
-			if !acquire(&report.inFlight, w.maxInFlight, &report.peakInFlight) {
-				report.dropped.Add(1)
+			if r.admit(p) {
+				r.dispatch(p, arrival, phase.chooser.choose(&random), nil)
+			}
+		}
+	}
+}
+
+// Pause suspends scheduling in every active run of the workload until Resume.
+// Phase clocks stop while paused, so the remaining schedule is shifted rather
+// than skipped. Outstanding requests are unaffected.
+func (w *Workload) Pause() { w.pause.pause() }
+
+// Resume continues scheduling after Pause.
+func (w *Workload) Resume() { w.pause.resume() }
+
+// SetRate replaces the offered rate of the phase at index, including any ramp,
+// in active and future runs. A zero rate restores the phase's own schedule.
+// Trace, burst, custom, and registered schedules have no rate and cannot be adjusted.
+// Under Spec.MaxRPS, rates above the phase's headroom are rejected.
+func (w *Workload) SetRate(phase int, rps uint64) error {
+	if phase < 0 || phase >= len(w.phases) {
+		return fmt.Errorf("phase %d does not exist", phase)
+	}
+	if !w.phases[phase].phase.hasRate() {
+		return fmt.Errorf("phase %d has no rate", phase)
+	}
+	if ceiling := w.phases[phase].ceiling; ceiling != 0 && rps > ceiling {
+		return fmt.Errorf("phase %d: rate %d exceeds the %d rps left under the maximum", phase, rps, ceiling)
+	}
+	w.phases[phase].override.Store(rps)
+	return nil
+}
+
+// shift is how far pauses have moved the run's schedule.
+func (r *run) shift() time.Duration {
+	paused, _ := r.workload.pause.state()
+	return paused - r.pauseBase
+}
+
+// elapsed is the time since the run started, excluding pauses.
+func (r *run) elapsed() time.Duration {
+	return r.since(r.started) - r.shift()
+}
+
+// since is the time elapsed on the workload's clock since t.
+func (r *run) since(t time.Time) time.Duration {
+	return r.workload.clock.Now().Sub(t)
+}
+
+// at returns the wall-clock time of an offset in the run's schedule.
+func (r *run) at(offset time.Duration) time.Time {
+	return r.started.Add(r.shift() + offset)
+}
+
+// waitUntil sleeps until offset in the run's schedule, following pauses that
+// begin or end while it sleeps.
+func (r *run) waitUntil(timer Timer, offset, spin time.Duration) bool {
+	for {
+		paused, resumed := r.workload.pause.state()
+		if resumed != nil {
+			select {
+			case <-r.controlCtx.Done():
+				return false
+			case <-resumed:
 				continue
 			}
-			endpoint := phase.chooser.choose(&random)
-			report.issued.Add(1)
-			requests.Add(1)
-			go func() {
-				defer requests.Done()
-				defer report.inFlight.Add(^uint64(0))
-				defer report.completed.Add(1)
-				endpoint.execute(requestsCtx)
-			}()
+		}
+		if !waitUntilTimer(r.controlCtx, r.workload.clock, timer, r.started.Add(paused-r.pauseBase+offset), spin) {
+			return false
+		}
+		if current, _ := r.workload.pause.state(); current == paused {
+			return true
+		}
+	}
+}
+
+// admit records one timely arrival and reserves an in-flight slot for it.
+func (r *run) admit(p *phaseRun) bool {
+	p.counts.scheduled.Add(1)
+	if acquire(&r.report.inFlight, r.workload.maxInFlight, &r.report.peakInFlight) {
+		return true
+	}
+	if r.workload.whenFull == DelayWhenFull {
+		p.counts.delayed.Add(1)
+		for {
+			released := r.released.wait()
+			if acquire(&r.report.inFlight, r.workload.maxInFlight, &r.report.peakInFlight) {
+				return true
+			}
+			select {
+			case <-r.controlCtx.Done():
+				p.counts.dropped.Add(1)
+				return false
+			case <-released:
+			}
+		}
+	}
+	p.counts.dropped.Add(1)
+	return false
+}
+
+// release frees an in-flight slot reserved by admit.
+func (r *run) release() {
+	r.report.inFlight.Add(^uint64(0))
+	if r.workload.whenFull == DelayWhenFull {
+		r.released.notify()
+	}
+}
+
+// dispatch executes an admitted request on its own goroutine or, with a worker
+// pool, hands it to an idle worker. Arrivals finding the pool queue full are
+// dropped. done, when non-nil, is signalled after the request completes.
+func (r *run) dispatch(p *phaseRun, a arrival, endpoint Endpoint, done chan<- struct{}) bool {
+	r.requests.Add(1)
+	p.requests.Add(1)
+	if r.queue == nil {
+		p.issued(a)
+		go r.execute(p, a, endpoint, done)
+		return true
+	}
+	select {
+	case r.queue <- queuedRequest{phase: p, arrival: a, endpoint: endpoint, done: done}:
+		p.issued(a)
+		return true
+	default:
+		p.requests.Done()
+		r.requests.Done()
+		r.release()
+		p.counts.dropped.Add(1)
+		return false
+	}
+}
+
+func (p *phaseRun) issued(a arrival) {
+	p.counts.issued.Add(1)
+	if a.warmUp {
+		p.counts.warmUp.Add(1)
+	}
+}
+
+func (r *run) work(worker int) {
+	for request := range r.queue {
+		if _, ok := WorkerFromContext(request.arrival.ctx); !ok {
+			request.arrival.ctx = &workerContext{Context: request.arrival.ctx, worker: worker}
+		}
+		r.execute(request.phase, request.arrival, request.endpoint, request.done)
+	}
+}
+
+func (r *run) execute(p *phaseRun, a arrival, endpoint Endpoint, done chan<- struct{}) {
+	defer r.requests.Done()
+	defer p.requests.Done()
+	defer r.release()
+	defer p.counts.completed.Add(1)
+	lag := uint64(max(r.since(a.scheduled), 0))
+	r.report.lag.Add(lag)
+	for current := r.report.maxLag.Load(); lag > current && !r.report.maxLag.CompareAndSwap(current, lag); current = r.report.maxLag.Load() {
+	}
+	timeout := p.phase.phase.RequestTimeout
+	if timeout == 0 {
+		timeout = r.workload.requestTimeout
+	}
+	var completed completion
+	if timeout == 0 {
+		completed = endpoint.execute(a.ctx)
+	} else {
+		ctx, cancel := context.WithTimeout(a.ctx, timeout)
+		completed = endpoint.execute(ctx)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			completed.timedOut = true
+			p.counts.timedOut.Add(1)
+		}
+		cancel()
+	}
+	if completed.failed {
+		p.counts.failed.Add(1)
+	}
+	if !a.warmUp {
+		r.spendBudget(p, completed.failed)
+		for i := range r.windows {
+			r.windows[i].record(completed)
+		}
+		if len(r.workload.thresholds) != 0 {
+			r.measured.record(completed)
+		}
+		if len(r.workload.observers) != 0 {
+			r.observe(a.ctx, completed)
+		}
+	}
+	if done != nil {
+		done <- struct{}{}
+	}
+}
+
+// spendBudget records a measured result and stops the run once the phase's
+// error budget is exceeded.
+func (r *run) spendBudget(p *phaseRun, failed bool) {
+	budget := p.phase.phase.ErrorBudget
+	if budget == nil {
+		return
+	}
+	completed := p.measured.Add(1)
+	failures := p.measuredFailed.Load()
+	if failed {
+		failures = p.measuredFailed.Add(1)
+	}
+	if (budget.MaxErrors != 0 && failures > budget.MaxErrors) ||
+		(budget.MaxRate != 0 && completed >= budget.MinRequests && float64(failures) > budget.MaxRate*float64(completed)) {
+		if p.budgetExceeded.CompareAndSwap(false, true) {
+			r.abort(fmt.Errorf("phase %d: %w", p.phase.info.Index, ErrErrorBudgetExceeded))
 		}
 	}
 }
 
 func (p *compiledPhase) rateAt(elapsed time.Duration) uint64 {
+	rate := p.nominalRateAt(elapsed)
+	if p.phase.Noise != 0 && p.override.Load() == 0 && p.adapted.Load() == 0 {
+		return p.noisy(rate, elapsed)
+	}
+	return rate
+}
+
+// noisy varies rate by up to the phase's Noise, drawing once for each second
+// of the phase from its seed.
+func (p *compiledPhase) noisy(rate uint64, elapsed time.Duration) uint64 {
+	draw := splitMix64(^p.seed + uint64(elapsed/time.Second))
+	deviation := (float64(draw>>11)/(1<<53)*2 - 1) * p.phase.Noise
+	return max(uint64(math.Round(float64(rate)*(1+deviation))), 1)
+}
+
+// nominalRateAt is the phase's rate before noise.
+func (p *compiledPhase) nominalRateAt(elapsed time.Duration) uint64 {
+	if override := p.override.Load(); override != 0 {
+		return override
+	}
+	if adapted := p.adapted.Load(); adapted != 0 {
+		return adapted
+	}
 	if p.phase.Ramp == nil {
 		return p.phase.RPS
 	}
@@ -293,38 +1269,54 @@ func (p *compiledPhase) rateAt(elapsed time.Duration) uint64 {
 	return start - steps*step
 }
 
-func batchInterval(rps uint64) time.Duration {
-	if rps < 1000 {
+// spin is how long before each batch the phase's scheduler busy-waits.
+func (p *compiledPhase) spin() time.Duration {
+	if p.resolution < time.Millisecond {
+		return spinWindow
+	}
+	return 0
+}
+
+func batchInterval(rps uint64, resolution time.Duration) time.Duration {
+	if rps < uint64(time.Second/resolution) {
 		return time.Second / time.Duration(rps)
 	}
-	return schedulerResolution
+	return resolution
 }
 
 func arrivalsForInterval(rps uint64, interval time.Duration, remainder *uint64) uint64 {
-	if rps < 1000 {
+	ticks := uint64(time.Second / interval)
+	if rps < ticks {
 		return 1
 	}
-	whole, fraction := rps/1000, rps%1000
+	whole, fraction := rps/ticks, rps%ticks
 	*remainder += fraction
-	if *remainder >= 1000 {
+	if *remainder >= ticks {
 		whole++
-		*remainder -= 1000
+		*remainder -= ticks
 	}
 	return whole
 }
 
-func waitUntilTimer(ctx context.Context, timer *time.Timer, target time.Time) bool {
-	delay := time.Until(target)
-	if delay <= 0 {
-		return ctx.Err() == nil
+// waitUntilTimer sleeps until target, busy-waiting for the final spin duration.
+func waitUntilTimer(ctx context.Context, clock Clock, timer Timer, target time.Time, spin time.Duration) bool {
+	if _, ok := clock.(systemClock); !ok {
+		// Only real sleeps overshoot; other clocks fire timers exactly.
+		spin = 0
 	}
-	timer.Reset(delay)
-	select {
-	case <-ctx.Done():
-		return false
-	case <-timer.C:
-		return true
+	delay := target.Sub(clock.Now()) - spin
+	if delay > 0 {
+		timer.Reset(delay)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C():
+		}
+	}
+	for clock.Now().Before(target) {
+		runtime.Gosched()
 	}
+	return ctx.Err() == nil
 }
 
 func acquire(inFlight *atomic.Uint64, maximum uint64, peak *atomic.Uint64) bool {
@@ -341,6 +1333,68 @@ func acquire(inFlight *atomic.Uint64, maximum uint64, peak *atomic.Uint64) bool
 	}
 }
 
+// pauseClock accumulates the time a workload spends paused.
+type pauseClock struct {
+	clock   Clock
+	mu      sync.Mutex
+	paused  bool
+	since   time.Time
+	total   time.Duration
+	resumed chan struct{}
+}
+
+func (c *pauseClock) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused, c.since, c.resumed = true, c.clock.Now(), make(chan struct{})
+	}
+}
+
+func (c *pauseClock) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused, c.total = false, c.total+c.clock.Now().Sub(c.since)
+		close(c.resumed)
+	}
+}
+
+// state returns the total paused time and, while paused, a channel closed on resume.
+func (c *pauseClock) state() (time.Duration, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		return c.total + c.clock.Now().Sub(c.since), c.resumed
+	}
+	return c.total, nil
+}
+
+// broadcast wakes every current waiter. Waiters must fetch the channel before
+// re-checking their condition so a notification in between is not lost.
+type broadcast struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (b *broadcast) wait() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ch == nil {
+		b.ch = make(chan struct{})
+	}
+	return b.ch
+}
+
+func (b *broadcast) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ch != nil {
+		close(b.ch)
+		b.ch = nil
+	}
+}
+
 // aliasChooser implements O(1) weighted endpoint selection. It is immutable
 // after workload compilation and each phase owns its random state.
 type aliasChooser struct {
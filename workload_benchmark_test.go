@@ -38,7 +38,10 @@ func BenchmarkWorkloadRun100kRPS(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for b.Loop() {
-		report := workload.Run(ctx)
+		report, err := workload.Run(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
 		if report.Completed != report.Issued || report.Scheduled != report.Issued+report.Missed {
 			b.Fatalf("scheduled=%d issued=%d completed=%d", report.Scheduled, report.Issued, report.Completed)
 		}
@@ -47,6 +50,21 @@ func BenchmarkWorkloadRun100kRPS(b *testing.B) {
 	}
 }
 
+func BenchmarkRampingPhaseSchedule(b *testing.B) {
+	phase := &compiledPhase{
+		phase:      Phase{Duration: 24 * time.Hour, RPS: 100, Ramp: &Ramp{To: 50_000, Step: 100, Every: time.Second}},
+		resolution: defaultResolution,
+	}
+	pacer := phase.newPacer()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, ok := pacer.Next(); !ok {
+			pacer = phase.newPacer()
+		}
+	}
+}
+
 func mustBenchmarkWorkload(b *testing.B, rps uint64, duration time.Duration) *Workload {
 	b.Helper()
 	workload, err := NewWorkload(Spec{
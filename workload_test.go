@@ -2,14 +2,21 @@ package go_loadgen
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 )
 
 type testRequest struct{}
-type testResult struct{}
+type testResult struct{ failed bool }
+
+func (r testResult) Failed() bool { return r.failed }
 
 type testProvider struct{}
 
@@ -22,24 +29,38 @@ func (*testCollector) Close()               {}
 
 func TestRunDrainsRequestsAfterSchedulingEnds(t *testing.T) {
 	started := make(chan struct{})
+	var once sync.Once
 	release := make(chan struct{})
 	client := testClient(func(context.Context, testRequest) testResult {
-		select {
-		case <-started:
-		default:
-			close(started)
-		}
+		once.Do(func() { close(started) })
 		<-release
 		return testResult{}
 	})
+	// The schedule runs on a fake clock, so a loaded machine cannot wake
+	// the scheduler past every arrival's deadline.
+	clock := NewFakeClock(time.Unix(0, 0))
 	workload := mustWorkload(t, Spec{
 		Duration:  time.Second,
+		Clock:     clock,
 		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
 		Phases:    []Phase{{Duration: 10 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
 	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for clock.BlockUntilContext(ctx, 1) == nil {
+			clock.Advance(time.Millisecond)
+		}
+	}()
 
 	done := make(chan Report, 1)
-	go func() { done <- workload.Run(context.Background()) }()
+	go func() {
+		report, err := workload.Run(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		done <- report
+	}()
 	select {
 	case <-started:
 	case <-time.After(time.Second):
@@ -78,7 +99,7 @@ func TestRunCancelsRequestsAfterDrainTimeout(t *testing.T) {
 		Phases:       []Phase{{Duration: 5 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
 	})
 
-	report := workload.Run(context.Background())
+	report := mustRun(t, workload)
 	select {
 	case <-cancelled:
 	default:
@@ -89,6 +110,123 @@ func TestRunCancelsRequestsAfterDrainTimeout(t *testing.T) {
 	}
 }
 
+type closingCollector struct {
+	closed    atomic.Bool
+	collected atomic.Uint64
+	late      atomic.Uint64
+}
+
+func (c *closingCollector) Collect(testResult) {
+	if c.closed.Load() {
+		c.late.Add(1)
+	}
+	c.collected.Add(1)
+}
+
+func (c *closingCollector) Close() { c.closed.Store(true) }
+
+func TestRunReturnsAfterLastResultIsCollected(t *testing.T) {
+	collector := &closingCollector{}
+	// The client ignores cancellation, so results arrive after the drain timeout.
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(30 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:     time.Second,
+		DrainTimeout: 5 * time.Millisecond,
+		Endpoints:    map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, collector)},
+		Phases:       []Phase{{Duration: 10 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	collector.Close()
+	time.Sleep(40 * time.Millisecond)
+	if !report.DrainTimedOut || collector.collected.Load() != report.Completed || collector.late.Load() != 0 {
+		t.Fatalf("timed_out=%t completed=%d collected=%d late=%d, want every result collected before Run returns", report.DrainTimedOut, report.Completed, collector.collected.Load(), collector.late.Load())
+	}
+}
+
+func TestPhaseDrainTimeoutCancelsBeforeLaterPhasesEnd(t *testing.T) {
+	cancelled := make(chan time.Duration, 1)
+	started := time.Now()
+	slow := testClient(func(ctx context.Context, _ testRequest) testResult {
+		<-ctx.Done()
+		select {
+		case cancelled <- time.Since(started):
+		default:
+		}
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration: time.Second,
+		Endpoints: map[string]Endpoint{
+			"slow": mustEndpoint(t, slow, testProvider{}, &testCollector{}),
+			"fast": &countingEndpoint{},
+		},
+		Phases: []Phase{
+			{Duration: 5 * time.Millisecond, RPS: 1000, DrainTimeout: 10 * time.Millisecond, Targets: []Target{{Endpoint: "slow", Weight: 1}}},
+			{Duration: 200 * time.Millisecond, RPS: 100, Targets: []Target{{Endpoint: "fast", Weight: 1}}},
+		},
+	})
+
+	report := mustRun(t, workload)
+	select {
+	case elapsed := <-cancelled:
+		if elapsed >= 150*time.Millisecond {
+			t.Fatalf("phase requests cancelled after %s, want before the later phase ends", elapsed)
+		}
+	default:
+		t.Fatal("phase drain timeout did not cancel outstanding requests")
+	}
+	if !report.DrainTimedOut || report.Completed != report.Issued {
+		t.Fatalf("timeout=%t issued=%d completed=%d", report.DrainTimedOut, report.Issued, report.Completed)
+	}
+}
+
+func TestRequestTimeoutBoundsEachRequest(t *testing.T) {
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		<-ctx.Done()
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:       time.Second,
+		RequestTimeout: 5 * time.Millisecond,
+		Endpoints:      map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:         []Phase{{Duration: 10 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if report.Issued == 0 || report.TimedOut != report.Issued || report.DrainTimedOut {
+		t.Fatalf("issued=%d timed_out=%d drain_timeout=%t, want every request to hit its deadline", report.Issued, report.TimedOut, report.DrainTimedOut)
+	}
+}
+
+func TestPhaseRequestTimeoutOverridesWorkloadTimeout(t *testing.T) {
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		select {
+		case <-ctx.Done():
+		case <-time.After(20 * time.Millisecond):
+		}
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:       time.Second,
+		RequestTimeout: 5 * time.Millisecond,
+		Endpoints:      map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases: []Phase{
+			{Name: "ping", Duration: 10 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Name: "upload", Duration: 10 * time.Millisecond, RPS: 500, RequestTimeout: time.Second, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+
+	report := mustRun(t, workload)
+	ping, upload := report.Phases[0], report.Phases[1]
+	if ping.Issued == 0 || ping.TimedOut != ping.Issued || upload.Issued == 0 || upload.TimedOut != 0 {
+		t.Fatalf("phases=%+v, want only the ping phase to time out", report.Phases)
+	}
+}
+
 func TestMaxInFlightDropsWithoutDelayingSchedule(t *testing.T) {
 	release := make(chan struct{})
 	client := testClient(func(context.Context, testRequest) testResult {
@@ -106,12 +244,280 @@ func TestMaxInFlightDropsWithoutDelayingSchedule(t *testing.T) {
 		time.Sleep(30 * time.Millisecond)
 		close(release)
 	}()
-	report := workload.Run(context.Background())
+	report := mustRun(t, workload)
 	if report.PeakInFlight != 2 || report.Issued != 2 || report.Dropped == 0 {
 		t.Fatalf("peak=%d issued=%d dropped=%d, want capped open-loop issuance", report.PeakInFlight, report.Issued, report.Dropped)
 	}
 }
 
+func TestMaxInFlightDelayPolicyWaitsForSlot(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(5 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:    time.Second,
+		MaxInFlight: 1,
+		WhenFull:    DelayWhenFull,
+		Endpoints:   map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:      []Phase{{Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if report.PeakInFlight != 1 || report.Delayed == 0 || report.Dropped != 0 {
+		t.Fatalf("peak=%d delayed=%d dropped=%d, want delayed arrivals without drops", report.PeakInFlight, report.Delayed, report.Dropped)
+	}
+	if report.Scheduled != report.Issued+report.Missed || report.Completed != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d missed=%d completed=%d", report.Scheduled, report.Issued, report.Missed, report.Completed)
+	}
+}
+
+func TestWorkerPoolBoundsConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	var running, peak atomic.Int64
+	client := testClient(func(context.Context, testRequest) testResult {
+		current := running.Add(1)
+		for current > peak.Load() && !peak.CompareAndSwap(peak.Load(), current) {
+		}
+		<-release
+		running.Add(-1)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Workers:   2,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 10 * time.Millisecond, RPS: 10_000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		close(release)
+	}()
+	report := mustRun(t, workload)
+	if peak.Load() != 2 || report.Issued > 4 || report.Dropped == 0 || report.Completed != report.Issued {
+		t.Fatalf("running=%d issued=%d dropped=%d completed=%d, want two workers and a two-request queue", peak.Load(), report.Issued, report.Dropped, report.Completed)
+	}
+}
+
+func TestLagMeasuresQueuedRequestsFromScheduledTime(t *testing.T) {
+	var untagged atomic.Uint64
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		if at, ok := ScheduledAt(ctx); !ok || at.After(time.Now()) {
+			untagged.Add(1)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Workers:   1,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 20 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if untagged.Load() != 0 || report.Issued < 2 {
+		t.Fatalf("issued=%d untagged=%d, want every request to carry its scheduled time", report.Issued, untagged.Load())
+	}
+	if report.MaxLag < 5*time.Millisecond || report.MeanLag == 0 || report.MeanLag > report.MaxLag {
+		t.Fatalf("mean_lag=%s max_lag=%s, want the queued request's wait reported", report.MeanLag, report.MaxLag)
+	}
+}
+
+func TestErrorBudgetStopsRun(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult { return testResult{failed: true} })
+	workload := mustWorkload(t, Spec{
+		Duration:  2 * time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases: []Phase{
+			{Duration: time.Second, RPS: 1000, ErrorBudget: &ErrorBudget{MaxRate: 0.5, MinRequests: 20}, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{StartAt: time.Second, Duration: time.Second, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+
+	report, err := workload.Run(context.Background())
+	if !errors.Is(err, ErrErrorBudgetExceeded) || report.SchedulingDuration > 500*time.Millisecond {
+		t.Fatalf("err=%v scheduling=%s, want the run stopped early", err, report.SchedulingDuration)
+	}
+	if report.Phases[0].Failed != report.Failed || report.Phases[1].Scheduled != 0 {
+		t.Fatalf("phases=%+v, want failures attributed to the first phase", report.Phases)
+	}
+	if report.Failed < 20 || report.Failed != report.Completed || report.Completed != report.Issued {
+		t.Fatalf("issued=%d completed=%d failed=%d", report.Issued, report.Completed, report.Failed)
+	}
+}
+
+func TestClosedLoopUsersWaitForResponses(t *testing.T) {
+	client := testClient(func(context.Context, testRequest) testResult {
+		time.Sleep(20 * time.Millisecond)
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: 100 * time.Millisecond, RPS: 1000, Users: 2, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	if report.PeakInFlight > 2 || report.Issued == 0 || report.Issued > 14 {
+		t.Fatalf("peak=%d issued=%d, want at most one outstanding request per user", report.PeakInFlight, report.Issued)
+	}
+	if report.Missed == 0 || report.Scheduled != report.Issued+report.Missed || report.Completed != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d missed=%d completed=%d", report.Scheduled, report.Issued, report.Missed, report.Completed)
+	}
+}
+
+func TestRequestsCarryWorker(t *testing.T) {
+	var mu sync.Mutex
+	workers := map[int]int{}
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		worker, ok := WorkerFromContext(ctx)
+		if !ok {
+			worker = -1
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		workers[worker]++
+		return testResult{}
+	})
+	endpoint := mustEndpoint(t, client, testProvider{}, &testCollector{})
+	mustRun(t, mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases: []Phase{
+			{Duration: 20 * time.Millisecond, RPS: 200, Users: 3, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{StartAt: 30 * time.Millisecond, Duration: 20 * time.Millisecond, RPS: 200, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	}))
+	if len(workers) != 4 || workers[0] == 0 || workers[1] == 0 || workers[2] == 0 || workers[-1] == 0 {
+		t.Fatalf("requests by worker=%v, want the three users and untagged open-loop requests", workers)
+	}
+}
+
+type batchingCollector struct {
+	testCollector
+	batches atomic.Uint64
+	results atomic.Uint64
+}
+
+func (c *batchingCollector) CollectBatch(results []testResult) {
+	c.batches.Add(1)
+	c.results.Add(uint64(len(results)))
+}
+
+func TestEndpointsBatchResultsForBatchCollectors(t *testing.T) {
+	collector := &batchingCollector{}
+	client := testClient(func(context.Context, testRequest) testResult { return testResult{} })
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, collector)},
+		Phases:    []Phase{{Duration: 50 * time.Millisecond, RPS: 10_000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	if collector.results.Load() != report.Completed || collector.count.Load() != 0 {
+		t.Fatalf("batched=%d collected=%d completed=%d, want every result handed over in batches by the end of Run", collector.results.Load(), collector.count.Load(), report.Completed)
+	}
+	if batches := collector.batches.Load(); batches == 0 || batches*4 > report.Completed {
+		t.Fatalf("%d batches for %d results", batches, report.Completed)
+	}
+
+	adapted := BatchAdapter[testResult](&testCollector{})
+	adapted.CollectBatch(make([]testResult, 3))
+	if count := adapted.(batchAdapter[testResult]).Collector.(*testCollector).count.Load(); count != 3 {
+		t.Fatalf("adapter collected %d results, want each one", count)
+	}
+}
+
+func TestPauseShiftsRemainingSchedule(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases:    []Phase{{Duration: 100 * time.Millisecond, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	done := make(chan Report, 1)
+	go func() {
+		report, err := workload.Run(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		done <- report
+	}()
+	time.Sleep(40 * time.Millisecond)
+	workload.Pause()
+	paused := endpoint.count.Load()
+	time.Sleep(60 * time.Millisecond)
+	if got := endpoint.count.Load(); got > paused+1 {
+		t.Fatalf("executed %d requests while paused, want none after %d", got, paused)
+	}
+	workload.Resume()
+
+	report := <-done
+	if report.Paused < 50*time.Millisecond || report.SchedulingDuration < 150*time.Millisecond {
+		t.Fatalf("paused=%s scheduling=%s, want the schedule shifted by the pause", report.Paused, report.SchedulingDuration)
+	}
+	if report.Scheduled != 10 || report.Issued+report.Missed != 10 {
+		t.Fatalf("scheduled=%d issued=%d missed=%d, want the full schedule after resuming", report.Scheduled, report.Issued, report.Missed)
+	}
+}
+
+func TestWarmUpResultsAreDiscardedOrTagged(t *testing.T) {
+	for _, collect := range []bool{false, true} {
+		var tagged atomic.Uint64
+		client := testClient(func(ctx context.Context, _ testRequest) testResult {
+			if IsWarmUp(ctx) {
+				tagged.Add(1)
+			}
+			return testResult{}
+		})
+		collector := &testCollector{}
+		workload := mustWorkload(t, Spec{
+			Duration:  time.Second,
+			Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, collector)},
+			Phases:    []Phase{{Duration: 50 * time.Millisecond, RPS: 1000, WarmUp: 20 * time.Millisecond, CollectWarmUp: collect, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+		})
+
+		report := mustRun(t, workload)
+		if report.WarmUp == 0 || report.WarmUp >= report.Issued || tagged.Load() != report.WarmUp {
+			t.Fatalf("collect=%t issued=%d warm_up=%d tagged=%d", collect, report.Issued, report.WarmUp, tagged.Load())
+		}
+		want := report.Issued - report.WarmUp
+		if collect {
+			want = report.Issued
+		}
+		if got := collector.count.Load(); got != want {
+			t.Fatalf("collect=%t collected=%d, want %d", collect, got, want)
+		}
+	}
+}
+
+func TestRequestsCarryPhaseInfo(t *testing.T) {
+	var seen sync.Map
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		if info, ok := PhaseFromContext(ctx); ok {
+			seen.Store(fmt.Sprintf("%d %s %s %d %s", info.Index, info.Name, info.Kind, info.RPS, info.Tags["variant"]), true)
+		}
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases: []Phase{
+			{Name: "steady", Tags: map[string]string{"variant": "a"}, Duration: 20 * time.Millisecond, RPS: 200, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+			{Name: "spike", StartAt: 20 * time.Millisecond, Duration: 20 * time.Millisecond, Burst: &Burst{Size: 5, Every: 10 * time.Millisecond}, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		},
+	})
+
+	mustRun(t, workload)
+	for _, want := range []string{"0 steady uniform 200 a", "1 spike burst 0 "} {
+		if _, ok := seen.Load(want); !ok {
+			t.Errorf("no request carried phase %q", want)
+		}
+	}
+}
+
 func TestAliasChooserRespectsWeights(t *testing.T) {
 	first := &countingEndpoint{}
 	second := &countingEndpoint{}
@@ -130,6 +536,143 @@ func TestAliasChooserRespectsWeights(t *testing.T) {
 	}
 }
 
+type otherProvider struct{}
+
+func (otherProvider) GetData() string { return "payload" }
+
+type otherCollector struct{ bytes atomic.Uint64 }
+
+func (c *otherCollector) Collect(length int) { c.bytes.Add(uint64(length)) }
+func (*otherCollector) Close()               {}
+
+type lengthClient struct{}
+
+func (lengthClient) CallEndpoint(_ context.Context, request string) int { return len(request) }
+
+func TestPhaseRoutesToEndpointsOfDifferentShapes(t *testing.T) {
+	typed := &testCollector{}
+	other := &otherCollector{}
+	workload := mustWorkload(t, Spec{
+		Duration: time.Second,
+		Endpoints: map[string]Endpoint{
+			"typed": mustEndpoint(t, testClient(func(context.Context, testRequest) testResult { return testResult{} }), testProvider{}, typed),
+			"other": mustEndpoint(t, lengthClient{}, otherProvider{}, other),
+		},
+		Phases: []Phase{{Duration: 50 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "typed", Weight: 1}, {Endpoint: "other", Weight: 1}}}},
+	})
+
+	report := mustRun(t, workload)
+	requests := other.bytes.Load() / uint64(len("payload"))
+	if typed.count.Load() == 0 || requests == 0 || typed.count.Load()+requests != report.Completed {
+		t.Fatalf("typed=%d other=%d completed=%d, want both endpoints exercised", typed.count.Load(), requests, report.Completed)
+	}
+}
+
+type sizedProvider int
+
+func (p sizedProvider) GetData() string { return strings.Repeat("x", int(p)) }
+
+func TestPhasesUseTheirOwnProviders(t *testing.T) {
+	large, small := &otherCollector{}, &otherCollector{}
+	workload := mustWorkload(t, Spec{
+		Duration: time.Second,
+		Endpoints: map[string]Endpoint{
+			"large": mustEndpoint(t, lengthClient{}, sizedProvider(1024), large),
+			"small": mustEndpoint(t, lengthClient{}, sizedProvider(8), small),
+		},
+		Phases: []Phase{
+			{Duration: 10 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "large", Weight: 1}}},
+			{StartAt: 10 * time.Millisecond, Duration: 10 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "small", Weight: 1}}},
+		},
+	})
+
+	report := mustRun(t, workload)
+	if report.Phases[0].Completed*1024 != large.bytes.Load() || report.Phases[1].Completed*8 != small.bytes.Load() {
+		t.Fatalf("phases=%+v large=%d small=%d, want each phase to send its own payloads", report.Phases, large.bytes.Load(), small.bytes.Load())
+	}
+}
+
+func TestSequenceSeparatesPhasesWithIdleGaps(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	step := Phase{Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}
+	phases := append(Sequence(30*time.Millisecond, step, step), Phase{Name: "cooldown", Idle: true, StartAt: 70 * time.Millisecond, Duration: 30 * time.Millisecond})
+	if len(phases) != 4 || phases[1].StartAt != 20*time.Millisecond || !phases[1].Idle || phases[2].StartAt != 50*time.Millisecond {
+		t.Fatalf("phases=%+v, want an idle gap between the steps", phases)
+	}
+	workload := mustWorkload(t, Spec{Duration: 100 * time.Millisecond, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: phases})
+
+	report := mustRun(t, workload)
+	if report.Phases[1].Scheduled != 0 || report.Phases[3].Scheduled != 0 || report.Scheduled != 20 {
+		t.Fatalf("phases=%+v, want traffic only outside idle phases", report.Phases)
+	}
+	if report.SchedulingDuration < 100*time.Millisecond {
+		t.Fatalf("scheduling=%s, want the run kept alive through the cooldown", report.SchedulingDuration)
+	}
+	if plan := workload.Plan(); plan.Expected != 20 || plan.Phases[3].Kind != "idle" {
+		t.Fatalf("plan=%+v", plan)
+	}
+	if _, err := NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: []Phase{{Idle: true, Duration: time.Second, RPS: 1}}}); err == nil {
+		t.Fatal("expected an idle phase with a rate to be rejected")
+	}
+}
+
+func TestSequentialPhasesComputeStartOffsets(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	phases := []Phase{
+		{Name: "warm", Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+		{Name: "peak", Duration: 30 * time.Millisecond, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}},
+	}
+	workload := mustWorkload(t, Spec{Sequential: true, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: phases})
+	plan := workload.Plan()
+	if plan.Duration != 50*time.Millisecond || plan.Phases[1].StartAt != 20*time.Millisecond || plan.Expected != 40 {
+		t.Fatalf("plan=%+v, want peak to start when warm ends and the run to cover both", plan)
+	}
+	if report := mustRun(t, workload); report.Phases[0].Scheduled != 10 || report.Phases[1].Scheduled != 30 {
+		t.Fatalf("phases=%+v, want each phase to run its full duration", report.Phases)
+	}
+
+	phases[1].StartAt = 10 * time.Millisecond
+	if _, err := NewWorkload(Spec{Sequential: true, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: phases}); err == nil {
+		t.Fatal("expected a sequential phase with a start offset to be rejected")
+	}
+}
+
+func TestRepeatLoopsScheduleWithIterations(t *testing.T) {
+	var iterations [3]atomic.Uint64
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		if info, ok := PhaseFromContext(ctx); ok {
+			iterations[info.Iteration].Add(1)
+		}
+		return testResult{}
+	})
+	endpoints := map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})}
+	phases := []Phase{{Name: "profile", Duration: 20 * time.Millisecond, RPS: 500, Targets: []Target{{Endpoint: "one", Weight: 1}}}}
+	workload := mustWorkload(t, Spec{Duration: 20 * time.Millisecond, Repeat: 3, Endpoints: endpoints, Phases: phases})
+
+	report := mustRun(t, workload)
+	if len(report.Phases) != 3 || report.Scheduled != 30 {
+		t.Fatalf("phases=%+v, want the profile scheduled three times", report.Phases)
+	}
+	for i := range iterations {
+		if got := iterations[i].Load(); got == 0 || got != report.Phases[i].Issued {
+			t.Errorf("iteration %d carried %d requests, want the %d its phase issued", i, got, report.Phases[i].Issued)
+		}
+	}
+
+	repeated := mustWorkload(t, Spec{Duration: 20 * time.Millisecond, RepeatFor: 50 * time.Millisecond, Endpoints: endpoints, Phases: phases})
+	if plan := repeated.Plan(); plan.Duration != 40*time.Millisecond || plan.Phases[1].StartAt != 20*time.Millisecond {
+		t.Fatalf("plan=%+v, want the whole iterations that fit", plan)
+	}
+	for _, spec := range []Spec{
+		{Duration: 20 * time.Millisecond, Repeat: 2, RepeatFor: time.Second, Endpoints: endpoints, Phases: phases},
+		{Duration: 20 * time.Millisecond, RepeatFor: 10 * time.Millisecond, Endpoints: endpoints, Phases: phases},
+	} {
+		if _, err := NewWorkload(spec); err == nil {
+			t.Fatalf("expected repeat %d for %s to be rejected", spec.Repeat, spec.RepeatFor)
+		}
+	}
+}
+
 func TestNewWorkloadRejectsInvalidDefinitions(t *testing.T) {
 	_, err := NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": &countingEndpoint{}}, Phases: []Phase{{Duration: time.Second, RPS: 1, Targets: []Target{{Endpoint: "missing", Weight: 1}}}}})
 	if err == nil {
@@ -158,6 +701,131 @@ func TestCompiledRampDoesNotRetainCallerPointer(t *testing.T) {
 	}
 }
 
+func TestSetRateOverridesSchedule(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:  2 * time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Duration: 2 * time.Second, RPS: 10, Ramp: &Ramp{To: 100, Step: 10, Every: time.Second}, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	if err := workload.SetRate(0, 500); err != nil {
+		t.Fatal(err)
+	}
+	if got := workload.phases[0].rateAt(time.Second); got != 500 {
+		t.Fatalf("overridden rate=%d, want 500", got)
+	}
+	if err := workload.SetRate(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := workload.phases[0].rateAt(time.Second); got != 20 {
+		t.Fatalf("restored rate=%d, want 20", got)
+	}
+	if err := workload.SetRate(1, 500); err == nil {
+		t.Fatal("expected unknown phase to be rejected")
+	}
+}
+
+func TestMaxRPSBoundsOverlappingPhases(t *testing.T) {
+	targets := []Target{{Endpoint: "one", Weight: 1}}
+	spec := Spec{
+		Duration:  3 * time.Second,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		MaxRPS:    100,
+		Phases: []Phase{
+			{Duration: 2 * time.Second, RPS: 40, Targets: targets},
+			{StartAt: time.Second, Duration: 2 * time.Second, RPS: 10, Ramp: &Ramp{To: 70, Step: 10, Every: 100 * time.Millisecond}, Targets: targets},
+			{StartAt: time.Second, Duration: time.Second, Burst: &Burst{Size: 500, Every: 100 * time.Millisecond}, Targets: targets},
+		},
+	}
+	if _, err := NewWorkload(spec); err == nil || !strings.Contains(err.Error(), "up to 110 rps") {
+		t.Fatalf("err=%v, want the ramp's peak to exceed the maximum with the first phase", err)
+	}
+	spec.Phases[1].Ramp.To = 50
+	workload := mustWorkload(t, spec)
+	if err := workload.SetRate(0, 60); err == nil {
+		t.Fatal("expected a rate above the headroom to be rejected")
+	}
+	if err := workload.SetRate(0, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := workload.SetRate(1, 60); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoiseVariesRatePerSecond(t *testing.T) {
+	spec := Spec{
+		Duration:  10 * time.Second,
+		Seed:      7,
+		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:    []Phase{{Duration: 10 * time.Second, RPS: 1000, Noise: 0.2, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	}
+	workload := mustWorkload(t, spec)
+	phase := &workload.phases[0]
+	rates := make(map[uint64]bool)
+	for second := range 10 {
+		at := time.Duration(second) * time.Second
+		rate := phase.rateAt(at)
+		if rate < 800 || rate > 1200 || rate != phase.rateAt(at+999*time.Millisecond) {
+			t.Fatalf("second %d: rate=%d, want one rate within 20%% of 1000", second, rate)
+		}
+		rates[rate] = true
+	}
+	if len(rates) < 5 {
+		t.Fatalf("rates=%v, want the rate to vary between seconds", rates)
+	}
+	plan := workload.Plan()
+	if plan.Phases[0].RPS != 1000 || plan.Expected == 10000 || plan.Expected != mustWorkload(t, spec).Plan().Expected {
+		t.Fatalf("plan=%+v, want nominal bounds and a reproducible noisy count", plan)
+	}
+	if err := workload.SetRate(0, 500); err != nil || phase.rateAt(time.Second) != 500 {
+		t.Fatal("expected SetRate to replace the noisy rate")
+	}
+
+	spec.Phases[0].Noise = 1.5
+	if _, err := NewWorkload(spec); err == nil {
+		t.Fatal("expected noise above one to be rejected")
+	}
+	spec.Phases[0] = Phase{Duration: time.Second, Burst: &Burst{Size: 1, Every: time.Second}, Noise: 0.1, Targets: spec.Phases[0].Targets}
+	if _, err := NewWorkload(spec); err == nil {
+		t.Fatal("expected noise on a burst phase to be rejected")
+	}
+}
+
+func TestRampHoldsEachStep(t *testing.T) {
+	phase := compiledPhase{phase: Phase{RPS: 100, Ramp: &Ramp{To: 400, Step: 100, Every: 10 * time.Minute}}}
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    uint64
+	}{
+		{0, 100},
+		{10*time.Minute - time.Nanosecond, 100},
+		{10 * time.Minute, 200},
+		{25 * time.Minute, 300},
+		{30 * time.Minute, 400},
+		{2 * time.Hour, 400},
+	} {
+		if got := phase.rateAt(tc.elapsed); got != tc.want {
+			t.Fatalf("rate at %s=%d, want %d", tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestRampingScheduleDoesNotAllocatePerStep(t *testing.T) {
+	phase := &compiledPhase{
+		phase:      Phase{Duration: time.Hour, RPS: 10, Ramp: &Ramp{To: 5_000, Step: 10, Every: time.Second}},
+		resolution: defaultResolution,
+	}
+	pacer := phase.newPacer()
+	allocs := testing.AllocsPerRun(100_000, func() {
+		if _, ok := pacer.Next(); !ok {
+			t.Fatal("ramp schedule ended early")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("ramp schedule allocated %.2f times per batch, want none", allocs)
+	}
+}
+
 func TestRateAtAndHighRateBatchingDoNotOverflow(t *testing.T) {
 	phase := compiledPhase{phase: Phase{RPS: math.MaxUint64 - 10, Ramp: &Ramp{To: math.MaxUint64, Step: 10, Every: time.Second}}}
 	if got := phase.rateAt(2 * time.Second); got != math.MaxUint64 {
@@ -176,6 +844,102 @@ func TestRateAtAndHighRateBatchingDoNotOverflow(t *testing.T) {
 	}
 }
 
+func TestPoissonPacerMatchesOfferedRate(t *testing.T) {
+	phase := &compiledPhase{phase: Phase{Duration: 10 * time.Second, RPS: 2_000, Arrivals: PoissonArrivals}, seed: 7, resolution: defaultResolution}
+	pacer := phase.newPacer()
+	var total, batches, largest uint64
+	previous := time.Duration(-1)
+	for {
+		batch, ok := pacer.Next()
+		if !ok {
+			break
+		}
+		if batch.At <= previous || batch.At > phase.phase.Duration || batch.Deadline <= batch.At {
+			t.Fatalf("invalid batch %+v after %s", batch, previous)
+		}
+		previous = batch.At
+		total += batch.Count
+		batches++
+		largest = max(largest, batch.Count)
+	}
+	if total < 19_400 || total > 20_600 {
+		t.Fatalf("poisson pacer issued %d arrivals, want approximately 20000", total)
+	}
+	if largest < 3 || batches >= total {
+		t.Fatalf("largest batch=%d batches=%d, want bursty arrivals", largest, batches)
+	}
+}
+
+func TestTracePhaseReplaysOffsets(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader("# captured arrivals\n0.02\n5ms\n\n5ms\n30ms\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}; !slices.Equal(trace, want) {
+		t.Fatalf("trace=%v, want %v", trace, want)
+	}
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases:    []Phase{{Duration: 50 * time.Millisecond, Trace: trace, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	if report.Scheduled != 4 || report.Issued+report.Missed != 4 || endpoint.count.Load() != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d missed=%d executed=%d, want four trace arrivals", report.Scheduled, report.Issued, report.Missed, endpoint.count.Load())
+	}
+
+	_, err = NewWorkload(Spec{Duration: time.Second, Endpoints: map[string]Endpoint{"one": endpoint}, Phases: []Phase{{Duration: time.Second, Trace: []time.Duration{2 * time.Second}, Targets: []Target{{Endpoint: "one", Weight: 1}}}}})
+	if err == nil {
+		t.Fatal("expected trace offset outside the phase to be rejected")
+	}
+}
+
+func TestFineResolutionSpacesHighRates(t *testing.T) {
+	workload := mustWorkload(t, Spec{
+		Duration:   time.Second,
+		Resolution: 10 * time.Microsecond,
+		Endpoints:  map[string]Endpoint{"one": &countingEndpoint{}},
+		Phases:     []Phase{{Duration: time.Second, RPS: 100_000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	pacer := workload.phases[0].newPacer()
+	var total uint64
+	for {
+		batch, ok := pacer.Next()
+		if !ok {
+			break
+		}
+		if batch.Count != 1 || batch.At%(10*time.Microsecond) != 0 {
+			t.Fatalf("batch %+v, want one arrival every 10µs", batch)
+		}
+		total += batch.Count
+	}
+	if total != 100_000 {
+		t.Fatalf("pacer issued %d arrivals, want 100000", total)
+	}
+
+	_, err := NewWorkload(Spec{Duration: time.Second, Resolution: 3 * time.Millisecond, Endpoints: map[string]Endpoint{"one": &countingEndpoint{}}, Phases: []Phase{{Duration: time.Second, RPS: 1, Targets: []Target{{Endpoint: "one", Weight: 1}}}}})
+	if err == nil {
+		t.Fatal("expected resolution that does not divide a second to be rejected")
+	}
+}
+
+func TestBurstPhaseIssuesBatches(t *testing.T) {
+	endpoint := &countingEndpoint{}
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": endpoint},
+		Phases:    []Phase{{Duration: 50 * time.Millisecond, Burst: &Burst{Size: 20, Every: 20 * time.Millisecond}, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+	report := mustRun(t, workload)
+	if report.Scheduled != 60 || report.PeakInFlight > 60 || endpoint.count.Load() != report.Issued {
+		t.Fatalf("scheduled=%d issued=%d executed=%d, want three bursts of 20", report.Scheduled, report.Issued, endpoint.count.Load())
+	}
+	if err := workload.SetRate(0, 10); err == nil {
+		t.Fatal("expected burst phase rate adjustment to be rejected")
+	}
+}
+
 func TestRunWithCancelledContextDoesNotIssueRequests(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -184,12 +948,37 @@ func TestRunWithCancelledContextDoesNotIssueRequests(t *testing.T) {
 		Endpoints: map[string]Endpoint{"one": &countingEndpoint{}},
 		Phases:    []Phase{{Duration: time.Second, RPS: 1000, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
 	})
-	report := workload.Run(ctx)
+	report, err := workload.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want cancellation", err)
+	}
 	if report.Issued != 0 || report.Completed != 0 {
 		t.Fatalf("issued=%d completed=%d after cancellation", report.Issued, report.Completed)
 	}
 }
 
+func TestRunStopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := testClient(func(ctx context.Context, _ testRequest) testResult {
+		<-ctx.Done()
+		return testResult{}
+	})
+	workload := mustWorkload(t, Spec{
+		Duration:  time.Second,
+		Endpoints: map[string]Endpoint{"one": mustEndpoint(t, client, testProvider{}, &testCollector{})},
+		Phases:    []Phase{{Duration: time.Second, RPS: 100, Targets: []Target{{Endpoint: "one", Weight: 1}}}},
+	})
+
+	time.AfterFunc(30*time.Millisecond, cancel)
+	report, err := workload.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want cancellation", err)
+	}
+	if report.Duration > 500*time.Millisecond || report.Issued == 0 || report.Completed != report.Issued {
+		t.Fatalf("duration=%s issued=%d completed=%d, want scheduling and requests cancelled", report.Duration, report.Issued, report.Completed)
+	}
+}
+
 type testClient func(context.Context, testRequest) testResult
 
 func (f testClient) CallEndpoint(ctx context.Context, request testRequest) testResult {
@@ -202,7 +991,16 @@ func (*nilTestClient) CallEndpoint(context.Context, testRequest) testResult { re
 
 type countingEndpoint struct{ count atomic.Uint64 }
 
-func (e *countingEndpoint) execute(context.Context) { e.count.Add(1) }
+func (e *countingEndpoint) execute(context.Context) completion {
+	e.count.Add(1)
+	return completion{}
+}
+
+func (e *countingEndpoint) collectorErr() error { return nil }
+
+func (e *countingEndpoint) flush() {}
+
+func (e *countingEndpoint) batching() bool { return false }
 
 func mustWorkload(t *testing.T, spec Spec) *Workload {
 	t.Helper()
@@ -213,6 +1011,15 @@ func mustWorkload(t *testing.T, spec Spec) *Workload {
 	return workload
 }
 
+func mustRun(t *testing.T, workload *Workload) Report {
+	t.Helper()
+	report, err := workload.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
 func mustEndpoint[C any, R any](t testing.TB, client Client[C, R], provider DataProvider[C], collector Collector[R]) Endpoint {
 	t.Helper()
 	endpoint, err := NewEndpoint(client, provider, collector)